@@ -8,21 +8,34 @@
 //	@SecurityDefinitions.apiKey	Bearer
 //	@in							header
 //	@name						Authorization
+
+//	@SecurityDefinitions.apiKey	ApiKey
+//	@in							header
+//	@name						X-API-Key
 //	@schemes					http https
 
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
 
 	_ "github.com/arasan1289/hexagonal-demo/docs"
 	"github.com/arasan1289/hexagonal-demo/internal/adapters/config"
 	"github.com/arasan1289/hexagonal-demo/internal/adapters/handlers/http"
+	"github.com/arasan1289/hexagonal-demo/internal/adapters/keyset"
+	"github.com/arasan1289/hexagonal-demo/internal/adapters/kms"
 	"github.com/arasan1289/hexagonal-demo/internal/adapters/logger"
+	"github.com/arasan1289/hexagonal-demo/internal/adapters/notification"
+	redis "github.com/arasan1289/hexagonal-demo/internal/adapters/storage/cache"
 	postgres "github.com/arasan1289/hexagonal-demo/internal/adapters/storage/db"
 	"github.com/arasan1289/hexagonal-demo/internal/adapters/storage/db/repository"
 	"github.com/arasan1289/hexagonal-demo/internal/core/domain"
+	"github.com/arasan1289/hexagonal-demo/internal/core/port"
 	"github.com/arasan1289/hexagonal-demo/internal/core/service"
 )
 
@@ -39,36 +52,88 @@ func main() {
 		fmt.Println("Error initializing logger:", err)
 		os.Exit(1)
 	}
-	// Initialize Gorm custom logger
-	customGormLogger := logger.NewGormLogger()
-	// Initialize DB
-	conn, err := postgres.New(config, customGormLogger)
+	if config.App.BlindIndexKey == "" {
+		log.Error().Msg("Blind index key is not configured")
+		os.Exit(1)
+	}
+	// Run the re-encryption job instead of the HTTP server, it returns once its connections are closed
+	if len(os.Args) > 1 && os.Args[1] == "reencrypt" {
+		os.Exit(reencrypt(log, config, os.Args[2:]))
+	}
+
+	conn, keyManager, err := connectDB(log, config)
 	if err != nil {
-		log.Error().Err(err).Msg("Error initializing Postgres DB")
 		os.Exit(1)
 	}
 	defer conn.Close()
-	log.Info().Msg("Successfully connected to DB")
 
-	// Migrate DB
-	conn.Migrate(&domain.User{})
+	// Initialize cache
+	cache, err := redis.New(context.Background(), config.Redis)
+	if err != nil {
+		log.Error().Err(err).Msg("Error initializing Redis cache")
+		os.Exit(1)
+	}
+	defer cache.Close()
+	log.Info().Msg("Successfully connected to cache")
 
-	log.Info().Msg("Successfully migrated user table")
+	// Initialize notification drivers
+	notifier, err := notification.New(config.Notification)
+	if err != nil {
+		log.Error().Err(err).Msg("Error initializing notification drivers")
+		os.Exit(1)
+	}
 
 	// Initialize Handlers
 	userRepo := repository.NewUserRepository(conn)
-	userSvc := service.NewUserService(userRepo, log)
-	UserHandler := http.NewUserHandler(userSvc, config.App, log)
 
-	authSvc := service.NewAuthService(log, config.App)
+	// Initialize JWT keys
+	keys, err := keyset.New(config.JWT)
+	if err != nil {
+		log.Error().Err(err).Msg("Error loading JWT keys")
+		os.Exit(1)
+	}
+	sessionRepo := repository.NewSessionRepository(conn)
+	authSvc := service.NewAuthService(log, config.App, keys, cache, userRepo, sessionRepo)
 
-	otpSvc := service.NewOtpService(log, config.App)
+	userSvc := service.NewUserService(userRepo, authSvc, keyManager, cache, notifier, log, config.App)
+	UserHandler := http.NewUserHandler(userSvc, config.App, log)
+
+	otpSvc := service.NewOtpService(log, config.App, cache, notifier)
 	OtpHandler := http.NewOtpHandler(otpSvc, userSvc, authSvc, log, config.App)
 
-	authhandler := http.NewAuthHandler(authSvc, userSvc, log)
+	magicLinkSvc := service.NewMagicLinkService(userRepo, cache, notifier, log, config.App)
+	authhandler := http.NewAuthHandler(authSvc, userSvc, magicLinkSvc, log)
+
+	passwordSvc := service.NewPasswordService(userRepo, authSvc, cache, notifier, log, config.App, config.PasswordPolicy)
+	passwordHandler := http.NewPasswordHandler(passwordSvc, log)
+
+	mfaSvc := service.NewMFAService(userRepo, authSvc, keyManager, cache, log, config.App)
+	mfaHandler := http.NewMFAHandler(mfaSvc, log)
+
+	apiKeyRepo := repository.NewAPIKeyRepository(conn)
+	apiKeySvc := service.NewAPIKeyService(apiKeyRepo, log)
+	apiKeyHandler := http.NewAPIKeyHandler(apiKeySvc, log)
+
+	oauthClientRepo := repository.NewOAuthClientRepository(conn)
+	oauthSvc := service.NewOAuthService(oauthClientRepo, authSvc, log)
+	oauthHandler := http.NewOAuthHandler(oauthSvc, authSvc, log)
+
+	outboxRepo := repository.NewOutboxRepository(conn)
+	exportSvc := service.NewDataExportService(userRepo, sessionRepo, outboxRepo, cache, notifier, log, config.App)
+	exportHandler := http.NewDataExportHandler(exportSvc, log)
+
+	// Start outbox dispatcher
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	outboxSvc := service.NewOutboxService(outboxRepo, userRepo, notifier, exportSvc, log, config.App)
+	go outboxSvc.Run(ctx)
+
+	// Start erasure of deleted users
+	erasureSvc := service.NewErasureService(userRepo, log, config.App)
+	go erasureSvc.Run(ctx)
 
 	// Initialize router
-	router, err := http.NewRouter(config, log, *UserHandler, *OtpHandler, authSvc, *authhandler)
+	router, err := http.NewRouter(config, log, *UserHandler, *OtpHandler, authSvc, *authhandler, *passwordHandler, *mfaHandler, apiKeySvc, *apiKeyHandler, *oauthHandler, *exportHandler)
 	if err != nil {
 		log.Error().Err(err).Msg("Error Initializing router")
 	}
@@ -83,3 +148,66 @@ func main() {
 	}
 
 }
+
+// connectDB connects to the DB with the key manager decrypting personal data and migrates the tables,
+// errors are logged
+func connectDB(log *logger.Logger, config *config.Container) (*postgres.Conn, port.IKeyManager, error) {
+	// Initialize Gorm custom logger
+	customGormLogger := logger.NewGormLogger()
+	// Initialize DB
+	conn, err := postgres.New(config, customGormLogger)
+	if err != nil {
+		log.Error().Err(err).Msg("Error initializing Postgres DB")
+		return nil, nil, err
+	}
+	log.Info().Msg("Successfully connected to DB")
+
+	// Initialize key manager, personal data is decrypted with the data keys it wraps
+	keyManager, err := kms.New(config.KMS)
+	if err != nil {
+		log.Error().Err(err).Msg("Error loading KMS master keys")
+		conn.Close()
+		return nil, nil, err
+	}
+	conn.SetKeyManager(keyManager)
+
+	// Migrate DB
+	conn.Migrate(&domain.User{}, &domain.OutboxMessage{}, &domain.Session{}, &domain.APIKey{}, &domain.OAuthClient{}, &domain.UserTombstone{})
+
+	log.Info().Msg("Successfully migrated tables")
+	return conn, keyManager, nil
+}
+
+// reencrypt runs the re-encryption job until every user is on the current keys and blind indexes, interrupting it logs
+// the ID to resume after. The exit code is returned once the DB connection is closed.
+// Usage: app reencrypt [-after <user id>] [-batch <size>]
+func reencrypt(log *logger.Logger, config *config.Container, args []string) int {
+	flags := flag.NewFlagSet("reencrypt", flag.ContinueOnError)
+	after := flags.String("after", "", "resume after this user ID")
+	batchSize := flags.Int("batch", 500, "users read per batch")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	conn, keyManager, err := connectDB(log, config)
+	if err != nil {
+		return 1
+	}
+	defer conn.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	reencryptionSvc := service.NewReencryptionService(repository.NewUserRepository(conn), keyManager, log, config.App)
+	progress, err := reencryptionSvc.Reencrypt(ctx, *after, *batchSize)
+	if err != nil {
+		log.Error().Err(err).Str("last_id", progress.LastID).Msg("Re-encryption stopped, resume with -after last_id")
+		return 1
+	}
+	log.Info().
+		Int("scanned", progress.Scanned).
+		Int("reencrypted", progress.Reencrypted).
+		Int("conflicts", progress.Conflicts).
+		Msg("Re-encryption finished")
+	return 0
+}
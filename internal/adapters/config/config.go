@@ -11,19 +11,44 @@ var k = koanf.New(".")
 
 type (
 	Container struct {
-		App  *App
-		DB   *DB
-		HTTP *HTTP
+		App            *App
+		DB             *DB
+		HTTP           *HTTP
+		Redis          *Redis
+		JWT            *JWT
+		Notification   *Notification
+		PasswordPolicy *PasswordPolicy
+		KMS            *KMS
 	}
 
 	App struct {
-		Name           string `koanf:"name"`
-		Env            string `koanf:"env"`
-		SecretKey      string `koanf:"secretKey"`    // Used for encrypting Email and phone number
-		OtpSecretKey   string `koanf:"otpSecretKey"` // Used for verifying OTP
-		OtpLength      uint   `koanf:"otp_length"`
-		QueryThreshold uint   `koanf:"query_threshold"`
-		JWTSecret      string `koanf:"jwtSecret"`
+		Name                string   `koanf:"name"`
+		Env                 string   `koanf:"env"`
+		SecretKey           string   `koanf:"secretKey"`       // Used for encrypting cached secrets, decrypts personal data encrypted before data keys were introduced
+		OldSecretKeys       []string `koanf:"old_secret_keys"` // Previous secret keys, only used for decrypting
+		OtpSecretKey        string   `koanf:"otpSecretKey"`    // Used for verifying OTP
+		OtpLength           uint     `koanf:"otp_length"`
+		OtpMaxAttempts      uint     `koanf:"otp_max_attempts"`    // Verify attempts allowed per OTP
+		OtpResendCooldown   uint     `koanf:"otp_resend_cooldown"` // Seconds before another OTP can be sent
+		QueryThreshold      uint     `koanf:"query_threshold"`
+		JWTSecret           string   `koanf:"jwtSecret"`
+		LoginMaxAttempts    uint     `koanf:"login_max_attempts"`   // Failed password logins before the account is locked
+		LoginLockout        uint     `koanf:"login_lockout"`        // Seconds of the first lockout, doubled with every further lockout
+		OutboxPollInterval  uint     `koanf:"outbox_poll_interval"` // Seconds between outbox polls
+		OutboxBatchSize     uint     `koanf:"outbox_batch_size"`
+		OutboxMaxAttempts   uint     `koanf:"outbox_max_attempts"`   // Publish attempts before a message is dead lettered
+		MagicLinkURL        string   `koanf:"magic_link_url"`        // Page the magic login link opens, the token is appended as the token query parameter
+		DataExportURL       string   `koanf:"data_export_url"`       // Download endpoint of data exports, the signed query parameters are appended
+		DeletionGracePeriod uint     `koanf:"deletion_grace_period"` // Days before the personal data of a deleted account is erased
+		// BlindIndexKey is the HMAC key the phone numbers and emails are hashed with for lookups. Users created
+		// before the hashes were keyed are moved to it by the reencrypt command, set BlindIndexBackfilled once it
+		// finished. To rotate, move the key and its version to the old fields, set the new key with a new version
+		// and run the reencrypt command, the old key is looked up until it is removed.
+		BlindIndexKey        string `koanf:"blindIndexKey"`
+		BlindIndexVersion    uint   `koanf:"blind_index_version"` // Version tag of the blind index key, defaults to 1
+		OldBlindIndexKey     string `koanf:"oldBlindIndexKey"`
+		OldBlindIndexVersion uint   `koanf:"old_blind_index_version"`
+		BlindIndexBackfilled bool   `koanf:"blind_index_backfilled"` // Stops looking up the unkeyed SHA-256 hashes
 	}
 
 	// Database contains all the environment variables for the database
@@ -42,6 +67,53 @@ type (
 		AllowedOrigins []string `koanf:"allowed_origins"`
 	}
 
+	// JWT contains the keys used for signing and verifying tokens.
+	// To rotate, add the new key without making it the signing key, wait until every
+	// verifier refreshed the JWKS, switch SigningKeyID and drop the old key once the
+	// tokens signed with it have expired.
+	JWT struct {
+		SigningKeyID string   `koanf:"signing_key_id"`
+		Keys         []JWTKey `koanf:"keys"`
+	}
+
+	// JWTKey is a single PEM encoded key pair, the private key is only needed for the signing key
+	JWTKey struct {
+		ID             string `koanf:"id"`
+		Algorithm      string `koanf:"algorithm"` // RS256 or EdDSA
+		PrivateKeyFile string `koanf:"private_key_file"`
+		PublicKeyFile  string `koanf:"public_key_file"`
+	}
+
+	// Notification contains the driver (console, file or webhook) of every notification channel
+	Notification struct {
+		SMS            string `koanf:"sms"`
+		Whatsapp       string `koanf:"whatsapp"`
+		Email          string `koanf:"email"`
+		Push           string `koanf:"push"`
+		FilePath       string `koanf:"file_path"`
+		WebhookURL     string `koanf:"webhook_url"`
+		WebhookToken   string `koanf:"webhook_token"`
+		WebhookTimeout uint   `koanf:"webhook_timeout"` // Seconds
+	}
+
+	// PasswordPolicy contains the rules new passwords are checked against, common passwords are always rejected
+	PasswordPolicy struct {
+		MinLength     uint `koanf:"min_length"`
+		RequireUpper  bool `koanf:"require_upper"`
+		RequireLower  bool `koanf:"require_lower"`
+		RequireDigit  bool `koanf:"require_digit"`
+		RequireSymbol bool `koanf:"require_symbol"`
+	}
+
+	// KMS contains the local master key file the data keys of users are wrapped with.
+	// To rotate, add a new version to the key file and make it the current version,
+	// the old versions are kept for unwrapping the data keys wrapped with them.
+	KMS struct {
+		KeyFile        string `koanf:"key_file"`
+		CurrentVersion uint   `koanf:"current_version"`
+	}
+
+	// Redis contains all the environment variables for the cache server
 	Redis struct {
 		Host     string `koanf:"host"`
 		Port     string `koanf:"port"`
@@ -50,6 +122,13 @@ type (
 	}
 )
 
+// SecretKeys returns the current secret key followed by the old secret keys, in the order ciphertexts are decrypted with.
+// To rotate the secret key, move it to the old secret keys, set the new key and run the reencrypt command, the old
+// key can be dropped once the command finished and the cached secrets encrypted with it expired.
+func (a *App) SecretKeys() []string {
+	return append([]string{a.SecretKey}, a.OldSecretKeys...)
+}
+
 // New returns a new container
 func New() (*Container, error) {
 	// Load config file.
@@ -65,6 +144,11 @@ func New() (*Container, error) {
 	var app App
 	var db DB
 	var http HTTP
+	var redis Redis
+	var jwt JWT
+	var notification Notification
+	var passwordPolicy PasswordPolicy
+	var kms KMS
 
 	if err := k.UnmarshalWithConf("", &app, koanf.UnmarshalConf{Tag: "koanf", FlatPaths: true}); err != nil {
 		return nil, err
@@ -78,8 +162,28 @@ func New() (*Container, error) {
 		return nil, err
 	}
 
+	if err := k.UnmarshalWithConf("redis", &redis, koanf.UnmarshalConf{Tag: "koanf", FlatPaths: true}); err != nil {
+		return nil, err
+	}
+
+	if err := k.UnmarshalWithConf("jwt", &jwt, koanf.UnmarshalConf{Tag: "koanf"}); err != nil {
+		return nil, err
+	}
+
+	if err := k.UnmarshalWithConf("notification", &notification, koanf.UnmarshalConf{Tag: "koanf", FlatPaths: true}); err != nil {
+		return nil, err
+	}
+
+	if err := k.UnmarshalWithConf("password_policy", &passwordPolicy, koanf.UnmarshalConf{Tag: "koanf", FlatPaths: true}); err != nil {
+		return nil, err
+	}
+
+	if err := k.UnmarshalWithConf("kms", &kms, koanf.UnmarshalConf{Tag: "koanf", FlatPaths: true}); err != nil {
+		return nil, err
+	}
+
 	return &Container{
-		App: &app, DB: &db, HTTP: &http,
+		App: &app, DB: &db, HTTP: &http, Redis: &redis, JWT: &jwt, Notification: &notification, PasswordPolicy: &passwordPolicy, KMS: &kms,
 	}, nil
 
 }
@@ -0,0 +1,111 @@
+package http
+
+import (
+	"time"
+
+	"github.com/arasan1289/hexagonal-demo/internal/adapters/logger"
+	"github.com/arasan1289/hexagonal-demo/internal/core/port"
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyHandler handles HTTP requests related to API key management
+type APIKeyHandler struct {
+	svc port.IAPIKeyService // API key service
+	log *logger.Logger      // logger
+}
+
+// NewAPIKeyHandler creates a new APIKeyHandler instance
+func NewAPIKeyHandler(svc port.IAPIKeyService, log *logger.Logger) *APIKeyHandler {
+	return &APIKeyHandler{
+		svc: svc,
+		log: log,
+	}
+}
+
+// createAPIKey is the request body for the create API key endpoint
+type createAPIKey struct {
+	Name      string     `json:"name" binding:"required,max=100" example:"billing-job"`
+	Scopes    []string   `json:"scopes" binding:"required,min=1" example:"users:read:any"`
+	ExpiresAt *time.Time `json:"expires_at" example:"2030-01-01T00:00:00Z"`
+}
+
+// @Summary			Create API key
+// @Description		Creates a scoped API key for a machine client, the key is only returned in this response
+// @Tags			Admin
+// @Produce			json
+// @Accept			json
+// @Param			apiKey	body		createAPIKey	true	"Create API Key JSON"
+// @Success			200		{object}	response{data=domain.NewAPIKey}
+// @Failure			400		{object}	response
+// @Failure			403		{object}	response
+// @Failure			500		{object}	response
+// @Router			/admin/api-keys [post]
+// @Security		Bearer
+func (kh *APIKeyHandler) CreateAPIKey(ctx *gin.Context) {
+	var req createAPIKey
+
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		validationError(ctx, err)
+		return
+	}
+	claims, err := getUserClaims(ctx)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+
+	key, err := kh.svc.CreateAPIKey(ctx, req.Name, req.Scopes, req.ExpiresAt, claims.Subject)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	handleSuccess(ctx, key)
+}
+
+// @Summary			List API keys
+// @Description		Lists all API keys without their secrets
+// @Tags			Admin
+// @Produce			json
+// @Success			200		{object}	response{data=[]domain.APIKey}
+// @Failure			403		{object}	response
+// @Failure			500		{object}	response
+// @Router			/admin/api-keys [get]
+// @Security		Bearer
+func (kh *APIKeyHandler) ListAPIKeys(ctx *gin.Context) {
+	keys, err := kh.svc.ListAPIKeys(ctx)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	handleSuccess(ctx, keys)
+}
+
+// apiKeyRequest is the uri of the API key endpoints
+type apiKeyRequest struct {
+	ID string `uri:"id" binding:"required"`
+}
+
+// @Summary			Revoke API key
+// @Description		Revokes an API key, requests made with it are rejected from then on
+// @Tags			Admin
+// @Produce			json
+// @Param			id		path		string	true	"API Key ID"
+// @Success			200		{object}	response
+// @Failure			403		{object}	response
+// @Failure			404		{object}	response
+// @Failure			500		{object}	response
+// @Router			/admin/api-keys/{id} [delete]
+// @Security		Bearer
+func (kh *APIKeyHandler) RevokeAPIKey(ctx *gin.Context) {
+	var req apiKeyRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		validationError(ctx, err)
+		return
+	}
+
+	if err := kh.svc.RevokeAPIKey(ctx, req.ID); err != nil {
+		handleError(ctx, err)
+		return
+	}
+	handleSuccess(ctx, nil)
+}
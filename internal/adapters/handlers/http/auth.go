@@ -1,6 +1,8 @@
 package http
 
 import (
+	"net/http"
+
 	"github.com/gin-gonic/gin"
 
 	"github.com/arasan1289/hexagonal-demo/internal/adapters/logger"
@@ -9,16 +11,18 @@ import (
 )
 
 type AuthHandler struct {
-	authSvc port.IAuthService // auth service
-	userSvc port.IUserService // user service
-	log     *logger.Logger    // logger
+	authSvc      port.IAuthService      // auth service
+	userSvc      port.IUserService      // user service
+	magicLinkSvc port.IMagicLinkService // magic link service
+	log          *logger.Logger         // logger
 }
 
-func NewAuthHandler(authSvc port.IAuthService, userSvc port.IUserService, log *logger.Logger) *AuthHandler {
+func NewAuthHandler(authSvc port.IAuthService, userSvc port.IUserService, magicLinkSvc port.IMagicLinkService, log *logger.Logger) *AuthHandler {
 	return &AuthHandler{
-		authSvc: authSvc,
-		userSvc: userSvc,
-		log:     log,
+		authSvc:      authSvc,
+		userSvc:      userSvc,
+		magicLinkSvc: magicLinkSvc,
+		log:          log,
 	}
 }
 
@@ -35,6 +39,7 @@ type loginUser struct {
 // @Accept			json
 // @Param			login	body		loginUser	true	"Login User JSON"
 // @Success			200		{object}	response{data=domain.JWTToken}
+// @Success			202		{object}	response{data=domain.MFAChallenge}
 // @Failure			400		{object}	response
 // @Failure			500		{object}	response
 // @Router			/login [post]
@@ -64,11 +69,237 @@ func (ah *AuthHandler) Login(ctx *gin.Context) {
 		handleError(ctx, domain.ErrInvalidCredentials)
 		return
 	}
-	tokens, err := ah.authSvc.GenerateJWT(ctx, user)
+	loginSuccess(ctx, ah.authSvc, user)
+}
+
+// loginSuccess responds with a token pair, or with an MFA challenge when the user has a second factor enabled
+func loginSuccess(ctx *gin.Context, authSvc port.IAuthService, user *domain.User) {
+	if user.IsTOTPEnabled {
+		mfaToken, err := authSvc.GenerateMFAToken(ctx, user)
+		if err != nil {
+			handleError(ctx, err)
+			return
+		}
+		rsp := newResponse(true, domain.MFAChallenge{MFARequired: true, MFAToken: mfaToken}, nil, nil)
+		ctx.JSON(http.StatusAccepted, rsp)
+		return
+	}
+
+	tokens, err := authSvc.GenerateJWT(ctx, user, clientInfo(ctx), false)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	handleSuccess(ctx, tokens)
+}
+
+// clientInfo describes the client of the request, the device name is optionally sent by apps in the X-Device-Name header
+func clientInfo(ctx *gin.Context) *domain.ClientInfo {
+	return &domain.ClientInfo{
+		DeviceName: ctx.GetHeader("X-Device-Name"),
+		UserAgent:  ctx.Request.UserAgent(),
+		IPAddress:  ctx.ClientIP(),
+	}
+}
+
+// requestMagicLink is the request body for the magic link endpoint
+type requestMagicLink struct {
+	Email  string `json:"email" binding:"required,email" example:"example@example.com"`
+	Locale string `json:"locale" binding:"omitempty,bcp47_language_tag" example:"en"`
+}
+
+// @Summary			Request magic link
+// @Description		Emails a single-use login link to a verified email, the response is the same for unknown emails
+// @Tags			Auth
+// @Produce			json
+// @Accept			json
+// @Param			magicLink	body		requestMagicLink	true	"Request Magic Link JSON"
+// @Success			200			{object}	response
+// @Failure			400			{object}	response
+// @Failure			500			{object}	response
+// @Router			/login/magic-link [post]
+func (ah *AuthHandler) RequestMagicLink(ctx *gin.Context) {
+	var req requestMagicLink
+
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		validationError(ctx, err)
+		return
+	}
+
+	if err := ah.magicLinkSvc.SendMagicLink(ctx, req.Email, req.Locale); err != nil {
+		handleError(ctx, err)
+		return
+	}
+	handleSuccess(ctx, nil)
+}
+
+// verifyMagicLink is the query of the verify magic link endpoint
+type verifyMagicLink struct {
+	Token string `form:"token" binding:"required"`
+}
+
+// @Summary			Verify magic link
+// @Description		Logs in with the token of a magic link, every link works once
+// @Tags			Auth
+// @Produce			json
+// @Param			token	query		string	true	"Magic link token"
+// @Success			200		{object}	response{data=domain.JWTToken}
+// @Success			202		{object}	response{data=domain.MFAChallenge}
+// @Failure			400		{object}	response
+// @Failure			401		{object}	response
+// @Failure			410		{object}	response
+// @Failure			500		{object}	response
+// @Router			/login/magic-link/verify [get]
+func (ah *AuthHandler) VerifyMagicLink(ctx *gin.Context) {
+	var req verifyMagicLink
+
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		validationError(ctx, err)
+		return
+	}
+
+	user, err := ah.magicLinkSvc.VerifyMagicLink(ctx, req.Token)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	loginSuccess(ctx, ah.authSvc, user)
+}
+
+// refreshToken is the request body for the refresh token endpoint
+type refreshToken struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// @Summary			Refresh token
+// @Description		Exchanges a refresh token for a new token pair, the used refresh token is invalidated
+// @Tags			Auth
+// @Produce			json
+// @Accept			json
+// @Param			refresh	body		refreshToken	true	"Refresh Token JSON"
+// @Success			200		{object}	response{data=domain.JWTToken}
+// @Failure			400		{object}	response
+// @Failure			401		{object}	response
+// @Failure			500		{object}	response
+// @Router			/token/refresh [post]
+func (ah *AuthHandler) RefreshToken(ctx *gin.Context) {
+	var req refreshToken
+
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		validationError(ctx, err)
+		return
+	}
+
+	tokens, err := ah.authSvc.RefreshJWT(ctx, req.RefreshToken)
 	if err != nil {
 		handleError(ctx, err)
 		return
 	}
 	handleSuccess(ctx, tokens)
+}
+
+// @Summary			Logout
+// @Description		Revokes the access token and the refresh token issued with it
+// @Tags			Auth
+// @Produce			json
+// @Success			200		{object}	response
+// @Failure			401		{object}	response
+// @Failure			500		{object}	response
+// @Router			/logout [post]
+// @Security		Bearer
+func (ah *AuthHandler) Logout(ctx *gin.Context) {
+	claims, err := getUserClaims(ctx)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+
+	if err := ah.authSvc.RevokeJWT(ctx, claims); err != nil {
+		handleError(ctx, err)
+		return
+	}
+	handleSuccess(ctx, nil)
+}
+
+// @Summary			Logout from all devices
+// @Description		Revokes every access and refresh token issued to the user
+// @Tags			Auth
+// @Produce			json
+// @Success			200		{object}	response
+// @Failure			401		{object}	response
+// @Failure			500		{object}	response
+// @Router			/logout-all [post]
+// @Security		Bearer
+func (ah *AuthHandler) LogoutAll(ctx *gin.Context) {
+	claims, err := getUserClaims(ctx)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+
+	if err := ah.authSvc.RevokeAllJWT(ctx, claims.Subject); err != nil {
+		handleError(ctx, err)
+		return
+	}
+	handleSuccess(ctx, nil)
+}
+
+// @Summary			List sessions
+// @Description		Lists the devices the authenticated user is logged in on
+// @Tags			Auth
+// @Produce			json
+// @Success			200		{object}	response{data=[]domain.Session}
+// @Failure			401		{object}	response
+// @Failure			500		{object}	response
+// @Router			/users/me/sessions [get]
+// @Security		Bearer
+func (ah *AuthHandler) ListSessions(ctx *gin.Context) {
+	claims, err := getUserClaims(ctx)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+
+	sessions, err := ah.authSvc.ListSessions(ctx, claims.Subject, claims.FamilyID)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	handleSuccess(ctx, sessions)
+}
 
+// @Summary			Revoke session
+// @Description		Logs the authenticated user out of one of their sessions
+// @Tags			Auth
+// @Produce			json
+// @Param			id		path		string	true	"Session ID"
+// @Success			200		{object}	response
+// @Failure			401		{object}	response
+// @Failure			500		{object}	response
+// @Router			/users/me/sessions/{id} [delete]
+// @Security		Bearer
+func (ah *AuthHandler) RevokeSession(ctx *gin.Context) {
+	claims, err := getUserClaims(ctx)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+
+	if err := ah.authSvc.RevokeSession(ctx, claims.Subject, ctx.Param("id")); err != nil {
+		handleError(ctx, err)
+		return
+	}
+	handleSuccess(ctx, nil)
+}
+
+// @Summary			JSON Web Key Set
+// @Description		Public keys for verifying tokens issued by this server, served in the RFC 7517 format
+// @Tags			Auth
+// @Produce			json
+// @Success			200		{object}	domain.JWKS
+// @Router			/.well-known/jwks.json [get]
+func (ah *AuthHandler) JWKS(ctx *gin.Context) {
+	// Verifiers may cache the keys, a rotated key is published before it is used for signing
+	ctx.Header("Cache-Control", "public, max-age=300")
+	ctx.JSON(http.StatusOK, ah.authSvc.JWKS(ctx))
 }
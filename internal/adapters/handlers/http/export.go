@@ -0,0 +1,103 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/arasan1289/hexagonal-demo/internal/adapters/logger"
+	"github.com/arasan1289/hexagonal-demo/internal/core/port"
+	"github.com/gin-gonic/gin"
+)
+
+// DataExportHandler handles HTTP requests related to personal data exports
+type DataExportHandler struct {
+	svc port.IDataExportService // data export service
+	log *logger.Logger          // logger
+}
+
+// NewDataExportHandler creates a new DataExportHandler instance
+func NewDataExportHandler(svc port.IDataExportService, log *logger.Logger) *DataExportHandler {
+	return &DataExportHandler{
+		svc: svc,
+		log: log,
+	}
+}
+
+// requestExport is the request body for the request export endpoint
+type requestExport struct {
+	Locale string `json:"locale" binding:"omitempty,bcp47_language_tag" example:"en"`
+}
+
+// @Summary			Request data export
+// @Description		Starts an export of the personal data of the authenticated user, a single-use download link is sent once it is ready
+// @Tags			User
+// @Produce			json
+// @Accept			json
+// @Param			export	body		requestExport	false	"Request Export JSON"
+// @Success			202		{object}	response
+// @Failure			401		{object}	response
+// @Failure			429		{object}	response
+// @Failure			500		{object}	response
+// @Router			/users/me/export [post]
+// @Security		Bearer
+func (eh *DataExportHandler) RequestExport(ctx *gin.Context) {
+	var req requestExport
+
+	if ctx.Request.ContentLength > 0 {
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			validationError(ctx, err)
+			return
+		}
+	}
+	claims, err := getUserClaims(ctx)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+
+	if err := eh.svc.RequestExport(ctx, claims.Subject, req.Locale); err != nil {
+		handleError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusAccepted, newResponse(true, nil, nil, nil))
+}
+
+// downloadExport is the query of the signed download link
+type downloadExport struct {
+	ID        string `form:"id" binding:"required"`
+	Expires   string `form:"expires" binding:"required"`
+	Key       string `form:"key" binding:"required"`
+	Signature string `form:"signature" binding:"required"`
+}
+
+// @Summary			Download data export
+// @Description		Downloads the ZIP bundle of a data export with the signed link sent to the user, every link works once
+// @Tags			User
+// @Produce			application/zip
+// @Param			id			query		string	true	"Export ID"
+// @Param			expires		query		string	true	"Expiry of the link"
+// @Param			key			query		string	true	"Key the bundle is encrypted with"
+// @Param			signature	query		string	true	"Signature of the link"
+// @Success			200			{file}		binary
+// @Failure			400			{object}	response
+// @Failure			401			{object}	response
+// @Failure			410			{object}	response
+// @Failure			500			{object}	response
+// @Router			/users/me/export/download [get]
+func (eh *DataExportHandler) DownloadExport(ctx *gin.Context) {
+	var req downloadExport
+
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		validationError(ctx, err)
+		return
+	}
+
+	bundle, err := eh.svc.DownloadExport(ctx, req.ID, req.Expires, req.Key, req.Signature)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"data-export-%s.zip\"", req.ID))
+	ctx.Header("Cache-Control", "no-store")
+	ctx.Data(http.StatusOK, "application/zip", bundle)
+}
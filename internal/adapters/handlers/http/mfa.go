@@ -0,0 +1,119 @@
+package http
+
+import (
+	"github.com/arasan1289/hexagonal-demo/internal/adapters/logger"
+	"github.com/arasan1289/hexagonal-demo/internal/core/port"
+	"github.com/gin-gonic/gin"
+)
+
+// MFAHandler handles HTTP requests related to two-factor authentication
+type MFAHandler struct {
+	svc port.IMFAService // MFA service
+	log *logger.Logger   // logger
+}
+
+// NewMFAHandler creates a new MFAHandler instance
+func NewMFAHandler(svc port.IMFAService, log *logger.Logger) *MFAHandler {
+	return &MFAHandler{
+		svc: svc,
+		log: log,
+	}
+}
+
+// @Summary			Enroll TOTP
+// @Description		Generates a TOTP secret for the authenticated user, two-factor authentication is enabled once confirmed
+// @Tags			MFA
+// @Produce			json
+// @Success			200		{object}	response{data=domain.TOTPEnrollment}
+// @Failure			401		{object}	response
+// @Failure			409		{object}	response
+// @Failure			500		{object}	response
+// @Router			/users/me/mfa/totp [post]
+// @Security		Bearer
+func (mh *MFAHandler) EnrollTOTP(ctx *gin.Context) {
+	claims, err := getUserClaims(ctx)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+
+	enrollment, err := mh.svc.EnrollTOTP(ctx, claims.Subject)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	handleSuccess(ctx, enrollment)
+}
+
+// confirmTOTP is the request body for the confirm TOTP endpoint
+type confirmTOTP struct {
+	Code string `json:"code" binding:"required,len=6,numeric" example:"123456"`
+}
+
+// @Summary			Confirm TOTP
+// @Description		Enables two-factor authentication with a code of the enrolled secret and returns the recovery codes
+// @Tags			MFA
+// @Produce			json
+// @Accept			json
+// @Param			confirm	body		confirmTOTP	true	"Confirm TOTP JSON"
+// @Success			200		{object}	response{data=domain.RecoveryCodes}
+// @Failure			400		{object}	response
+// @Failure			401		{object}	response
+// @Failure			409		{object}	response
+// @Failure			500		{object}	response
+// @Router			/users/me/mfa/totp/confirm [post]
+// @Security		Bearer
+func (mh *MFAHandler) ConfirmTOTP(ctx *gin.Context) {
+	var req confirmTOTP
+
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		validationError(ctx, err)
+		return
+	}
+	claims, err := getUserClaims(ctx)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+
+	codes, err := mh.svc.ConfirmTOTP(ctx, claims.Subject, req.Code)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	handleSuccess(ctx, codes)
+}
+
+// verifyMFA is the request body for the MFA login endpoint
+type verifyMFA struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	Code     string `json:"code" binding:"required" example:"123456"`
+}
+
+// @Summary			Login with second factor
+// @Description		Exchanges the MFA token returned by a login and a TOTP or recovery code for a token pair
+// @Tags			Auth
+// @Produce			json
+// @Accept			json
+// @Param			verify	body		verifyMFA	true	"Verify MFA JSON"
+// @Success			200		{object}	response{data=domain.JWTToken}
+// @Failure			400		{object}	response
+// @Failure			401		{object}	response
+// @Failure			429		{object}	response
+// @Failure			500		{object}	response
+// @Router			/login/mfa [post]
+func (mh *MFAHandler) VerifyLogin(ctx *gin.Context) {
+	var req verifyMFA
+
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		validationError(ctx, err)
+		return
+	}
+
+	tokens, err := mh.svc.VerifyLogin(ctx, req.MFAToken, req.Code, clientInfo(ctx))
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	handleSuccess(ctx, tokens)
+}
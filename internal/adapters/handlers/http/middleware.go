@@ -14,6 +14,9 @@ import (
 	"github.com/rs/zerolog"
 )
 
+// authorizationPayloadKey is the context key under which the auth middleware stores the caller's claims
+const authorizationPayloadKey = "user"
+
 // NewRateLimiter creates a new rate limiter middleware using the token bucket strategy
 func NewRateLimiter(capacity float64, fillInterval time.Duration) gin.HandlerFunc {
 
@@ -77,7 +80,7 @@ func GinStructuredLogger(logger *logger.Logger) gin.HandlerFunc {
 			logEvent = logger.Info()
 		}
 
-		userClaims, ok := ctx.Value("user").(*domain.UserClaims)
+		userClaims, ok := ctx.Value(authorizationPayloadKey).(*domain.UserClaims)
 		if !ok || userClaims == nil {
 			logEvent.Str("user_id", "")
 		} else {
@@ -96,33 +99,118 @@ func GinStructuredLogger(logger *logger.Logger) gin.HandlerFunc {
 	}
 }
 
-// JWTAuthMiddleware is the middleware for JWT authentication
-func NewJWTAuthMiddleware(as port.IAuthService) gin.HandlerFunc {
+// JWTAuthMiddleware is the middleware for JWT authentication.
+// Machine clients authenticate with an API key, either in the X-API-Key header or with the ApiKey scheme.
+func NewJWTAuthMiddleware(as port.IAuthService, ks port.IAPIKeyService) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
-		authHeader := ctx.GetHeader("Authorization")
-		if authHeader == "" {
-			handleError(ctx, domain.ErrEmptyAuthorizationHeader)
+		var userClaims *domain.UserClaims
+		var err error
+
+		if apiKey := ctx.GetHeader("X-API-Key"); apiKey != "" {
+			userClaims, err = ks.VerifyAPIKey(ctx, apiKey)
+		} else {
+			authHeader := ctx.GetHeader("Authorization")
+			if authHeader == "" {
+				handleError(ctx, domain.ErrEmptyAuthorizationHeader)
+				ctx.Abort()
+				return
+			}
+
+			parts := strings.Split(authHeader, " ")
+			if len(parts) != 2 {
+				handleError(ctx, domain.ErrInvalidAuthorizationHeader)
+				ctx.Abort()
+				return
+			}
+
+			switch parts[0] {
+			case "Bearer":
+				userClaims, err = as.VerifyJWT(ctx, parts[1])
+			case "ApiKey":
+				userClaims, err = ks.VerifyAPIKey(ctx, parts[1])
+			default:
+				err = domain.ErrInvalidAuthorizationType
+			}
+		}
+		if err != nil {
+			handleError(ctx, err)
 			ctx.Abort()
 			return
 		}
 
-		parts := strings.Split(authHeader, " ")
-		if len(parts) != 2 || parts[0] != "Bearer" {
-			handleError(ctx, domain.ErrInvalidAuthorizationHeader)
+		// Add user information to the context
+		ctx.Set(authorizationPayloadKey, userClaims)
+		ctx.Next()
+	}
+}
+
+// RequireRoles allows the request only when the authenticated user has one of the given roles.
+// It must be registered after the auth middleware.
+func RequireRoles(roles ...domain.UserRole) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		userClaims, err := getUserClaims(ctx)
+		if err != nil {
+			handleError(ctx, err)
 			ctx.Abort()
 			return
 		}
 
-		tokenStr := parts[1]
-		userClaims, err := as.VerifyJWT(ctx, tokenStr)
+		for _, role := range roles {
+			if domain.UserRole(userClaims.Role) == role {
+				ctx.Next()
+				return
+			}
+		}
+		handleError(ctx, domain.ErrForbidden)
+		ctx.Abort()
+	}
+}
+
+// RequirePermission allows the request only when the role of the authenticated user grants the permission.
+// It must be registered after the auth middleware.
+func RequirePermission(perm domain.Permission) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		userClaims, err := getUserClaims(ctx)
 		if err != nil {
 			handleError(ctx, err)
 			ctx.Abort()
 			return
 		}
 
-		// Add user information to the context
-		ctx.Set("user", userClaims)
+		if !userClaims.HasPermission(perm) {
+			handleError(ctx, domain.ErrForbidden)
+			ctx.Abort()
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// RequireMFA allows the request only when the authenticated user logged in with a second factor.
+// It must be registered after the auth middleware.
+func RequireMFA() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		userClaims, err := getUserClaims(ctx)
+		if err != nil {
+			handleError(ctx, err)
+			ctx.Abort()
+			return
+		}
+
+		if !userClaims.MFA {
+			handleError(ctx, domain.ErrMFARequired)
+			ctx.Abort()
+			return
+		}
 		ctx.Next()
 	}
 }
+
+// getUserClaims returns the claims stored in the context by the auth middleware
+func getUserClaims(ctx *gin.Context) (*domain.UserClaims, error) {
+	userClaims, ok := ctx.Value(authorizationPayloadKey).(*domain.UserClaims)
+	if !ok || userClaims == nil {
+		return nil, domain.ErrUnauthorized
+	}
+	return userClaims, nil
+}
@@ -0,0 +1,287 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/arasan1289/hexagonal-demo/internal/adapters/logger"
+	"github.com/arasan1289/hexagonal-demo/internal/core/domain"
+	"github.com/arasan1289/hexagonal-demo/internal/core/port"
+	"github.com/gin-gonic/gin"
+)
+
+// OAuthHandler handles the OAuth2 authorization server endpoints and the OAuth client management
+type OAuthHandler struct {
+	svc     port.IOAuthService // OAuth service
+	authSvc port.IAuthService  // auth service
+	log     *logger.Logger     // logger
+}
+
+// NewOAuthHandler creates a new OAuthHandler instance
+func NewOAuthHandler(svc port.IOAuthService, authSvc port.IAuthService, log *logger.Logger) *OAuthHandler {
+	return &OAuthHandler{
+		svc:     svc,
+		authSvc: authSvc,
+		log:     log,
+	}
+}
+
+// oauthTokenRequest is the form body of the token endpoint
+type oauthTokenRequest struct {
+	GrantType    string `form:"grant_type" binding:"required" example:"client_credentials"`
+	Scope        string `form:"scope" example:"users:read:any"`
+	RefreshToken string `form:"refresh_token"`
+}
+
+// @Summary			OAuth token
+// @Description		Issues tokens with the client_credentials or refresh_token grant (RFC 6749), clients authenticate with HTTP Basic or client_id and client_secret form fields
+// @Tags			OAuth
+// @Accept			x-www-form-urlencoded
+// @Produce			json
+// @Param			grant_type		formData	string	true	"Grant type"	Enums(client_credentials, refresh_token)
+// @Param			scope			formData	string	false	"Space separated scopes"
+// @Param			refresh_token	formData	string	false	"Refresh token"
+// @Success			200				{object}	domain.OAuthToken
+// @Failure			400				{object}	domain.OAuthError
+// @Failure			401				{object}	domain.OAuthError
+// @Router			/oauth/token [post]
+func (oh *OAuthHandler) Token(ctx *gin.Context) {
+	var req oauthTokenRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		oauthError(ctx, fmt.Errorf("%w: %s", domain.ErrValidation, err))
+		return
+	}
+
+	var token *domain.OAuthToken
+	switch req.GrantType {
+	case "client_credentials":
+		client, err := oh.authenticateClient(ctx)
+		if err != nil {
+			oauthError(ctx, err)
+			return
+		}
+		token, err = oh.svc.ClientCredentials(ctx, client, req.Scope)
+		if err != nil {
+			oauthError(ctx, err)
+			return
+		}
+	case "refresh_token":
+		if req.RefreshToken == "" {
+			oauthError(ctx, domain.ErrValidation)
+			return
+		}
+		// Refresh tokens are issued to public user clients, credentials are only checked when sent
+		if _, _, ok := clientCredentials(ctx); ok {
+			if _, err := oh.authenticateClient(ctx); err != nil {
+				oauthError(ctx, err)
+				return
+			}
+		}
+		tokens, err := oh.authSvc.RefreshJWT(ctx, req.RefreshToken)
+		if err != nil {
+			oauthError(ctx, err)
+			return
+		}
+		token = &domain.OAuthToken{
+			AccessToken:  tokens.AccessToken,
+			TokenType:    "Bearer",
+			ExpiresIn:    tokens.ExpiresIn,
+			RefreshToken: tokens.RefreshToken,
+		}
+	default:
+		oauthError(ctx, domain.ErrUnsupportedGrantType)
+		return
+	}
+
+	ctx.Header("Cache-Control", "no-store")
+	ctx.Header("Pragma", "no-cache")
+	ctx.JSON(http.StatusOK, token)
+}
+
+// oauthTokenForm is the form body of the introspection and revocation endpoints
+type oauthTokenForm struct {
+	Token         string `form:"token" binding:"required"`
+	TokenTypeHint string `form:"token_type_hint"`
+}
+
+// @Summary			OAuth token introspection
+// @Description		Reports whether a token is active and returns its claims (RFC 7662), the caller must authenticate as an OAuth client
+// @Tags			OAuth
+// @Accept			x-www-form-urlencoded
+// @Produce			json
+// @Param			token			formData	string	true	"Token"
+// @Param			token_type_hint	formData	string	false	"Token type hint"
+// @Success			200				{object}	domain.TokenIntrospection
+// @Failure			400				{object}	domain.OAuthError
+// @Failure			401				{object}	domain.OAuthError
+// @Router			/oauth/introspect [post]
+func (oh *OAuthHandler) Introspect(ctx *gin.Context) {
+	if _, err := oh.authenticateClient(ctx); err != nil {
+		oauthError(ctx, err)
+		return
+	}
+	var req oauthTokenForm
+	if err := ctx.ShouldBind(&req); err != nil {
+		oauthError(ctx, fmt.Errorf("%w: %s", domain.ErrValidation, err))
+		return
+	}
+
+	rsp, err := oh.authSvc.IntrospectJWT(ctx, req.Token)
+	if err != nil {
+		oauthError(ctx, err)
+		return
+	}
+	ctx.Header("Cache-Control", "no-store")
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// @Summary			OAuth token revocation
+// @Description		Revokes an access token issued to the calling OAuth client (RFC 7009), unknown tokens are ignored and tokens of other clients or users are refused
+// @Tags			OAuth
+// @Accept			x-www-form-urlencoded
+// @Produce			json
+// @Param			token			formData	string	true	"Token"
+// @Param			token_type_hint	formData	string	false	"Token type hint"
+// @Success			200
+// @Failure			400				{object}	domain.OAuthError
+// @Failure			401				{object}	domain.OAuthError
+// @Router			/oauth/revoke [post]
+func (oh *OAuthHandler) Revoke(ctx *gin.Context) {
+	client, err := oh.authenticateClient(ctx)
+	if err != nil {
+		oauthError(ctx, err)
+		return
+	}
+	var req oauthTokenForm
+	if err := ctx.ShouldBind(&req); err != nil {
+		oauthError(ctx, fmt.Errorf("%w: %s", domain.ErrValidation, err))
+		return
+	}
+
+	if err := oh.authSvc.RevokeToken(ctx, client.ID, req.Token); err != nil {
+		oauthError(ctx, err)
+		return
+	}
+	ctx.Status(http.StatusOK)
+}
+
+// registerOAuthClient is the request body for the register OAuth client endpoint
+type registerOAuthClient struct {
+	Name   string   `json:"name" binding:"required,max=100" example:"orders-service"`
+	Scopes []string `json:"scopes" binding:"required,min=1" example:"users:read:any"`
+}
+
+// @Summary			Register OAuth client
+// @Description		Registers a client for the client credentials grant, the secret is only returned in this response
+// @Tags			Admin
+// @Produce			json
+// @Accept			json
+// @Param			client	body		registerOAuthClient	true	"Register OAuth Client JSON"
+// @Success			200		{object}	response{data=domain.NewOAuthClient}
+// @Failure			400		{object}	response
+// @Failure			403		{object}	response
+// @Failure			500		{object}	response
+// @Router			/admin/oauth-clients [post]
+// @Security		Bearer
+func (oh *OAuthHandler) RegisterClient(ctx *gin.Context) {
+	var req registerOAuthClient
+
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		validationError(ctx, err)
+		return
+	}
+	claims, err := getUserClaims(ctx)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+
+	client, err := oh.svc.RegisterClient(ctx, req.Name, req.Scopes, claims.Subject)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	handleSuccess(ctx, client)
+}
+
+// oauthClientRequest is the uri of the OAuth client endpoints
+type oauthClientRequest struct {
+	ID string `uri:"id" binding:"required"`
+}
+
+// @Summary			Revoke OAuth client
+// @Description		Revokes an OAuth client, it can no longer obtain tokens
+// @Tags			Admin
+// @Produce			json
+// @Param			id		path		string	true	"Client ID"
+// @Success			200		{object}	response
+// @Failure			403		{object}	response
+// @Failure			404		{object}	response
+// @Failure			500		{object}	response
+// @Router			/admin/oauth-clients/{id} [delete]
+// @Security		Bearer
+func (oh *OAuthHandler) RevokeClient(ctx *gin.Context) {
+	var req oauthClientRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		validationError(ctx, err)
+		return
+	}
+
+	if err := oh.svc.RevokeClient(ctx, req.ID); err != nil {
+		handleError(ctx, err)
+		return
+	}
+	handleSuccess(ctx, nil)
+}
+
+// authenticateClient authenticates the OAuth client of the request
+func (oh *OAuthHandler) authenticateClient(ctx *gin.Context) (*domain.OAuthClient, error) {
+	clientID, clientSecret, _ := clientCredentials(ctx)
+	return oh.svc.AuthenticateClient(ctx, clientID, clientSecret)
+}
+
+// clientCredentials reads the client credentials from the HTTP Basic authorization header, whose values are
+// form encoded (RFC 6749 section 2.3.1), or else from the client_id and client_secret form fields
+func clientCredentials(ctx *gin.Context) (string, string, bool) {
+	if id, secret, ok := ctx.Request.BasicAuth(); ok {
+		id, errID := url.QueryUnescape(id)
+		secret, errSecret := url.QueryUnescape(secret)
+		return id, secret, errID == nil && errSecret == nil
+	}
+	id, secret := ctx.PostForm("client_id"), ctx.PostForm("client_secret")
+	return id, secret, id != "" || secret != ""
+}
+
+// oauthError writes the error in the OAuth error response format (RFC 6749 section 5.2)
+func oauthError(ctx *gin.Context, err error) {
+	ctx.Error(err)
+
+	status := http.StatusBadRequest
+	rsp := domain.OAuthError{Error: "invalid_request"}
+	switch {
+	case errors.Is(err, domain.ErrInvalidClient):
+		status = http.StatusUnauthorized
+		rsp.Error = "invalid_client"
+		ctx.Header("WWW-Authenticate", `Basic realm="oauth"`)
+	case errors.Is(err, domain.ErrUnsupportedGrantType):
+		rsp.Error = "unsupported_grant_type"
+	case errors.Is(err, domain.ErrUnauthorizedClient):
+		rsp.Error = "unauthorized_client"
+	case errors.Is(err, domain.ErrInvalidScope):
+		rsp.Error = "invalid_scope"
+	case errors.Is(err, domain.ErrInvalidToken), errors.Is(err, domain.ErrExpiredToken),
+		errors.Is(err, domain.ErrRevokedToken), errors.Is(err, domain.ErrRefreshTokenReused),
+		errors.Is(err, domain.ErrDataNotFound):
+		rsp.Error = "invalid_grant"
+	case errors.Is(err, domain.ErrValidation):
+	default:
+		status = http.StatusInternalServerError
+		rsp.Error = "server_error"
+	}
+	if status != http.StatusInternalServerError {
+		rsp.ErrorDescription = err.Error()
+	}
+	ctx.JSON(status, rsp)
+}
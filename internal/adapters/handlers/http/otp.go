@@ -26,16 +26,19 @@ func NewOtpHandler(svc port.IOtpService, userSvc port.IUserService, authSvc port
 // requestOtp is the request body for the request otp endpoint
 type requestOtp struct {
 	PhoneNumber string `json:"phone_number" binding:"required,min=10" example:"9876543210"`
+	Channel     string `json:"channel" binding:"omitempty,oneof=sms whatsapp email" example:"sms"`
+	Locale      string `json:"locale" binding:"omitempty,bcp47_language_tag" example:"en"`
 }
 
 //	@Summary		Request OTP
-//	@Description	Sends OTP to the number if its registered
+//	@Description	Sends OTP to the registered number or email through the requested channel, defaults to SMS
 //	@Tags			Auth
 //	@Produce		json
 //	@Accept			json
 //	@Param			sendOTP	body		requestOtp	true	"Request OTP JSON"
 //	@Success		200		{object}	response{data=domain.OTP}
 //	@Failure		400		{object}	response
+//	@Failure		429		{object}	response
 //	@Failure		500		{object}	response
 //	@Router			/send-otp [post]
 func (oh *OtpHandler) RequestOtp(ctx *gin.Context) {
@@ -45,12 +48,16 @@ func (oh *OtpHandler) RequestOtp(ctx *gin.Context) {
 		validationError(ctx, err)
 		return
 	}
-	_, err := oh.userSvc.GetUserByPhoneNumberOrEmail(ctx, req.PhoneNumber)
+	user, err := oh.userSvc.GetUserByPhoneNumberOrEmail(ctx, req.PhoneNumber)
 	if err != nil {
 		handleError(ctx, err)
 		return
 	}
-	rsp, err := oh.svc.GenerateOTP(ctx, oh.config.OtpLength)
+	channel := domain.ChannelSMS
+	if req.Channel != "" {
+		channel = domain.NotificationChannel(req.Channel)
+	}
+	rsp, err := oh.svc.SendOTP(ctx, user, channel, req.Locale)
 	if err != nil {
 		handleError(ctx, err)
 		return
@@ -62,8 +69,7 @@ func (oh *OtpHandler) RequestOtp(ctx *gin.Context) {
 // verifyOtp is the request body for the verify otp endpoint
 type verifyOtp struct {
 	Otp         string `json:"otp" binding:"required,min=6" example:"123456"`
-	OtpHash     string `json:"otp_hash" binding:"required"`
-	PhoneNumber string `json:"phone_number" binding:"required, min=10" example:"9876543210"`
+	PhoneNumber string `json:"phone_number" binding:"required,min=10" example:"9876543210"`
 }
 
 //	@Summary		Verify OTP
@@ -73,7 +79,9 @@ type verifyOtp struct {
 //	@Accept			json
 //	@Param			verifyOTP	body		verifyOtp	true	"Verify OTP JSON"
 //	@Success		200			{object}	response{data=domain.JWTToken}
+//	@Success		202			{object}	response{data=domain.MFAChallenge}
 //	@Failure		400			{object}	response
+//	@Failure		429			{object}	response
 //	@Failure		500			{object}	response
 //	@Router			/verify-otp [post]
 func (oh *OtpHandler) VerifyOtp(ctx *gin.Context) {
@@ -84,12 +92,7 @@ func (oh *OtpHandler) VerifyOtp(ctx *gin.Context) {
 		return
 	}
 
-	otp := domain.OTP{
-		Otp:     req.Otp,
-		OtpHash: req.OtpHash,
-	}
-
-	rsp, err := oh.svc.VerifyOTP(ctx, &otp)
+	rsp, err := oh.svc.VerifyOTP(ctx, req.PhoneNumber, req.Otp)
 	if err != nil {
 		handleError(ctx, err)
 		return
@@ -102,19 +105,12 @@ func (oh *OtpHandler) VerifyOtp(ctx *gin.Context) {
 	}
 
 	if rsp && !user.IsPhoneNumberVerified {
-		user.IsPhoneNumberVerified = true
-		user.IsActive = true
-		user, err = oh.userSvc.Register(ctx, user, oh.config)
+		user, err = oh.userSvc.ConfirmPhoneNumber(ctx, user)
 		if err != nil {
 			handleError(ctx, err)
 			return
 		}
 	}
 
-	tokens, err := oh.authSvc.GenerateJWT(ctx, user)
-	if err != nil {
-		handleError(ctx, err)
-		return
-	}
-	handleSuccess(ctx, tokens)
+	loginSuccess(ctx, oh.authSvc, user)
 }
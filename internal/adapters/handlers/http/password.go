@@ -0,0 +1,129 @@
+package http
+
+import (
+	"github.com/arasan1289/hexagonal-demo/internal/adapters/logger"
+	"github.com/arasan1289/hexagonal-demo/internal/core/domain"
+	"github.com/arasan1289/hexagonal-demo/internal/core/port"
+	"github.com/gin-gonic/gin"
+)
+
+// PasswordHandler handles HTTP requests related to password management
+type PasswordHandler struct {
+	svc port.IPasswordService // password service
+	log *logger.Logger        // logger
+}
+
+// NewPasswordHandler creates a new PasswordHandler instance
+func NewPasswordHandler(svc port.IPasswordService, log *logger.Logger) *PasswordHandler {
+	return &PasswordHandler{
+		svc: svc,
+		log: log,
+	}
+}
+
+// forgotPassword is the request body for the forgot password endpoint
+type forgotPassword struct {
+	PhoneNumber string `json:"phone_number,omitempty" binding:"required_without=Email,omitempty,min=10" example:"9876543210"`
+	Email       string `json:"email,omitempty" binding:"required_without=PhoneNumber,omitempty,email" example:"example@example.com"`
+	Channel     string `json:"channel" binding:"omitempty,oneof=sms whatsapp email" example:"email"`
+	Locale      string `json:"locale" binding:"omitempty,bcp47_language_tag" example:"en"`
+}
+
+// @Summary			Forgot password
+// @Description		Sends a single-use password reset token to the user, by email when set otherwise by SMS
+// @Tags			Auth
+// @Produce			json
+// @Accept			json
+// @Param			forgot	body		forgotPassword	true	"Forgot Password JSON"
+// @Success			200		{object}	response
+// @Failure			400		{object}	response
+// @Failure			500		{object}	response
+// @Router			/password/forgot [post]
+func (ph *PasswordHandler) ForgotPassword(ctx *gin.Context) {
+	var req forgotPassword
+
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		validationError(ctx, err)
+		return
+	}
+	identifier := req.PhoneNumber
+	if identifier == "" {
+		identifier = req.Email
+	}
+
+	err := ph.svc.ForgotPassword(ctx, identifier, domain.NotificationChannel(req.Channel), req.Locale)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	handleSuccess(ctx, nil)
+}
+
+// resetPassword is the request body for the reset password endpoint
+type resetPassword struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required" example:"password"`
+}
+
+// @Summary			Reset password
+// @Description		Sets a new password with the reset token and logs the user out everywhere
+// @Tags			Auth
+// @Produce			json
+// @Accept			json
+// @Param			reset	body		resetPassword	true	"Reset Password JSON"
+// @Success			200		{object}	response
+// @Failure			400		{object}	response
+// @Failure			500		{object}	response
+// @Router			/password/reset [post]
+func (ph *PasswordHandler) ResetPassword(ctx *gin.Context) {
+	var req resetPassword
+
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		validationError(ctx, err)
+		return
+	}
+
+	if err := ph.svc.ResetPassword(ctx, req.Token, req.Password); err != nil {
+		handleError(ctx, err)
+		return
+	}
+	handleSuccess(ctx, nil)
+}
+
+// changePassword is the request body for the change password endpoint
+type changePassword struct {
+	CurrentPassword string `json:"current_password" example:"password"`
+	NewPassword     string `json:"new_password" binding:"required" example:"n3w-Passw0rd"`
+}
+
+// @Summary			Set or change password
+// @Description		Sets the password of the authenticated user, the current password is required when one is already set
+// @Tags			User
+// @Produce			json
+// @Accept			json
+// @Param			password	body		changePassword	true	"Change Password JSON"
+// @Success			200			{object}	response
+// @Failure			400			{object}	response
+// @Failure			401			{object}	response
+// @Failure			500			{object}	response
+// @Router			/users/me/password [put]
+// @Security		Bearer
+func (ph *PasswordHandler) ChangePassword(ctx *gin.Context) {
+	var req changePassword
+
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		validationError(ctx, err)
+		return
+	}
+	claims, err := getUserClaims(ctx)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+
+	if err := ph.svc.ChangePassword(ctx, claims.Subject, req.CurrentPassword, req.NewPassword); err != nil {
+		handleError(ctx, err)
+		return
+	}
+	handleSuccess(ctx, nil)
+}
@@ -14,6 +14,7 @@ import (
 type response struct {
 	Success           bool              `json:"success"`
 	Data              any               `json:"data,omitempty"`
+	Meta              any               `json:"meta,omitempty"`
 	Errors            map[string]string `json:"errors,omitempty"`
 	DescriptiveErrors []ValidationError `json:"descriptive_errors,omitempty"`
 }
@@ -30,23 +31,57 @@ func newResponse(success bool, data any, errors map[string]string, descriptiveEr
 
 // errorStatusMap is a map of defined error messages and their corresponding http status codes
 var errorStatusMap = map[error]int{
-	domain.ErrDataNotFound:               http.StatusNotFound,
-	domain.ErrConflictingData:            http.StatusConflict,
-	domain.ErrInvalidCredentials:         http.StatusUnauthorized,
-	domain.ErrUnauthorized:               http.StatusUnauthorized,
-	domain.ErrEmptyAuthorizationHeader:   http.StatusUnauthorized,
-	domain.ErrInvalidAuthorizationHeader: http.StatusUnauthorized,
-	domain.ErrInvalidAuthorizationType:   http.StatusUnauthorized,
-	domain.ErrInvalidToken:               http.StatusUnauthorized,
-	domain.ErrExpiredToken:               http.StatusUnauthorized,
-	domain.ErrForbidden:                  http.StatusForbidden,
-	domain.ErrNoUpdatedData:              http.StatusBadRequest,
-	domain.ErrInternal:                   http.StatusInternalServerError,
-	domain.ErrRateLimitExceeded:          http.StatusTooManyRequests,
-	domain.ErrOTPExpired:                 http.StatusBadRequest,
-	domain.ErrOTPMismatch:                http.StatusBadRequest,
-	domain.ErrInvalidCredentials:         http.StatusBadRequest,
-	domain.ErrPasswordNotSet:             http.StatusBadRequest,
+	domain.ErrDataNotFound:                 http.StatusNotFound,
+	domain.ErrConflictingData:              http.StatusConflict,
+	domain.ErrInvalidCredentials:           http.StatusUnauthorized,
+	domain.ErrUnauthorized:                 http.StatusUnauthorized,
+	domain.ErrEmptyAuthorizationHeader:     http.StatusUnauthorized,
+	domain.ErrInvalidAuthorizationHeader:   http.StatusUnauthorized,
+	domain.ErrInvalidAuthorizationType:     http.StatusUnauthorized,
+	domain.ErrInvalidToken:                 http.StatusUnauthorized,
+	domain.ErrExpiredToken:                 http.StatusUnauthorized,
+	domain.ErrRevokedToken:                 http.StatusUnauthorized,
+	domain.ErrRefreshTokenReused:           http.StatusUnauthorized,
+	domain.ErrForbidden:                    http.StatusForbidden,
+	domain.ErrNoUpdatedData:                http.StatusBadRequest,
+	domain.ErrInternal:                     http.StatusInternalServerError,
+	domain.ErrRateLimitExceeded:            http.StatusTooManyRequests,
+	domain.ErrOTPExpired:                   http.StatusBadRequest,
+	domain.ErrOTPMismatch:                  http.StatusBadRequest,
+	domain.ErrOTPNotFound:                  http.StatusBadRequest,
+	domain.ErrOTPAttemptsExceeded:          http.StatusTooManyRequests,
+	domain.ErrOTPResendCooldown:            http.StatusTooManyRequests,
+	domain.ErrInvalidCredentials:           http.StatusBadRequest,
+	domain.ErrPasswordNotSet:               http.StatusBadRequest,
+	domain.ErrAccountLocked:                http.StatusLocked,
+	domain.ErrAccountInactive:              http.StatusForbidden,
+	domain.ErrInvalidResetToken:            http.StatusBadRequest,
+	domain.ErrPasswordTooShort:             http.StatusBadRequest,
+	domain.ErrPasswordTooWeak:              http.StatusBadRequest,
+	domain.ErrPasswordTooCommon:            http.StatusBadRequest,
+	domain.ErrUnsupportedChannel:           http.StatusBadRequest,
+	domain.ErrEmailNotSet:                  http.StatusBadRequest,
+	domain.ErrEmailNotVerified:             http.StatusForbidden,
+	domain.ErrInvalidVerificationCode:      http.StatusBadRequest,
+	domain.ErrVerificationAttemptsExceeded: http.StatusTooManyRequests,
+	domain.ErrNotificationFailed:           http.StatusBadGateway,
+	domain.ErrMFAAlreadyEnabled:            http.StatusConflict,
+	domain.ErrMFANotEnrolled:               http.StatusBadRequest,
+	domain.ErrInvalidMFACode:               http.StatusUnauthorized,
+	domain.ErrMFAAttemptsExceeded:          http.StatusTooManyRequests,
+	domain.ErrMFARequired:                  http.StatusForbidden,
+	domain.ErrInvalidAPIKey:                http.StatusUnauthorized,
+	domain.ErrInvalidScope:                 http.StatusBadRequest,
+	domain.ErrValidation:                   http.StatusBadRequest,
+	domain.ErrInvalidClient:                http.StatusUnauthorized,
+	domain.ErrInvalidMagicLink:             http.StatusUnauthorized,
+	domain.ErrMagicLinkUsed:                http.StatusGone,
+	domain.ErrMagicLinkExpired:             http.StatusGone,
+	domain.ErrUnsupportedGrantType:         http.StatusBadRequest,
+	domain.ErrUnauthorizedClient:           http.StatusForbidden,
+	domain.ErrExportPending:                http.StatusTooManyRequests,
+	domain.ErrInvalidDownloadLink:          http.StatusUnauthorized,
+	domain.ErrDownloadLinkExpired:          http.StatusGone,
 }
 
 // parseError parses error messages from the error object and returns a slice of error messages
@@ -95,6 +130,20 @@ func handleSuccess(ctx *gin.Context, data any) {
 	ctx.JSON(http.StatusOK, rsp)
 }
 
+// paginationMeta is the response meta of paginated lists, the next page is requested with NextCursor as cursor
+type paginationMeta struct {
+	Count      int    `json:"count"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	HasMore    bool   `json:"has_more"`
+}
+
+// handleSuccessWithMeta sends a success response with data and response metadata such as pagination
+func handleSuccessWithMeta(ctx *gin.Context, data any, meta any) {
+	rsp := newResponse(true, data, nil, nil)
+	rsp.Meta = meta
+	ctx.JSON(http.StatusOK, rsp)
+}
+
 // handleError sends a error response with the specified status code and error message
 func handleError(ctx *gin.Context, err error) {
 	switch err {
@@ -106,6 +155,13 @@ func handleError(ctx *gin.Context, err error) {
 	statusCode, ok := errorStatusMap[err]
 	if !ok {
 		statusCode = http.StatusInternalServerError
+		// Wrapped errors get the status code of the defined error they wrap
+		for target, code := range errorStatusMap {
+			if errors.Is(err, target) {
+				statusCode = code
+				break
+			}
+		}
 	}
 	errMsg, descriptiveErrs := parseError(ctx, err)
 	errRsp := newResponse(false, nil, errMsg, descriptiveErrs)
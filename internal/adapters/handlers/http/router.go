@@ -6,6 +6,7 @@ import (
 
 	"github.com/arasan1289/hexagonal-demo/internal/adapters/config"
 	"github.com/arasan1289/hexagonal-demo/internal/adapters/logger"
+	"github.com/arasan1289/hexagonal-demo/internal/core/domain"
 	"github.com/arasan1289/hexagonal-demo/internal/core/port"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -21,7 +22,7 @@ type Router struct {
 }
 
 // NewRouter creates a new Router instance
-func NewRouter(config *config.Container, log *logger.Logger, userHandler UserHandler, otpHandler OtpHandler, authService port.IAuthService, authhandler AuthHandler) (*Router, error) {
+func NewRouter(config *config.Container, log *logger.Logger, userHandler UserHandler, otpHandler OtpHandler, authService port.IAuthService, authhandler AuthHandler, passwordHandler PasswordHandler, mfaHandler MFAHandler, apiKeyService port.IAPIKeyService, apiKeyHandler APIKeyHandler, oauthHandler OAuthHandler, exportHandler DataExportHandler) (*Router, error) {
 	// Disable debug mode in production
 	if config.App.Env == "prod" {
 		gin.SetMode(gin.ReleaseMode)
@@ -52,19 +53,63 @@ func NewRouter(config *config.Container, log *logger.Logger, userHandler UserHan
 	rateLimit := NewRateLimiter(100, 60)
 
 	// JWT authorization middleware
-	authMiddleware := NewJWTAuthMiddleware(authService)
+	authMiddleware := NewJWTAuthMiddleware(authService, apiKeyService)
 
 	v1 := router.Group("/api/v1")
 	{
 		user := v1.Group("/users")
 		{
 			user.POST("/", userHandler.Register)
-			user.GET("/:id", authMiddleware, rateLimit, userHandler.GetUser)
+			user.GET("/:id", authMiddleware, rateLimit, RequirePermission(domain.PermUserReadOwn), userHandler.GetUser)
+			user.PUT("/me/password", authMiddleware, rateLimit, passwordHandler.ChangePassword)
+			user.PATCH("/me", authMiddleware, rateLimit, userHandler.UpdateProfile)
+			user.DELETE("/me", authMiddleware, rateLimit, userHandler.DeleteMe)
+			user.POST("/me/export", authMiddleware, rateLimit, exportHandler.RequestExport)
+			user.GET("/me/export/download", rateLimit, exportHandler.DownloadExport)
+			user.PUT("/me/email", authMiddleware, rateLimit, userHandler.ChangeEmail)
+			user.POST("/me/email/verify", authMiddleware, rateLimit, userHandler.VerifyEmail)
+			user.PUT("/me/phone-number", authMiddleware, rateLimit, userHandler.ChangePhoneNumber)
+			user.POST("/me/phone-number/verify", authMiddleware, rateLimit, userHandler.VerifyPhoneNumber)
+			user.GET("/me/sessions", authMiddleware, authhandler.ListSessions)
+			user.DELETE("/me/sessions/:id", authMiddleware, authhandler.RevokeSession)
+			user.POST("/me/mfa/totp", authMiddleware, rateLimit, mfaHandler.EnrollTOTP)
+			user.POST("/me/mfa/totp/confirm", authMiddleware, rateLimit, mfaHandler.ConfirmTOTP)
+		}
+		admin := v1.Group("/admin", authMiddleware, RequireRoles(domain.Admin), RequireMFA())
+		{
+			admin.GET("/users", userHandler.ListUsers)
+			admin.POST("/users/:id/unlock", userHandler.UnlockUser)
+			admin.PUT("/users/:id/role", userHandler.SetRole)
+			admin.PUT("/users/:id/status", userHandler.SetStatus)
+			admin.DELETE("/users/:id", userHandler.DeleteUser)
+			admin.POST("/api-keys", apiKeyHandler.CreateAPIKey)
+			admin.GET("/api-keys", apiKeyHandler.ListAPIKeys)
+			admin.DELETE("/api-keys/:id", apiKeyHandler.RevokeAPIKey)
+			admin.POST("/oauth-clients", oauthHandler.RegisterClient)
+			admin.DELETE("/oauth-clients/:id", oauthHandler.RevokeClient)
 		}
 		v1.POST("/send-otp", rateLimit, otpHandler.RequestOtp)
 		v1.POST("/verify-otp", rateLimit, otpHandler.VerifyOtp)
 		v1.POST("/login", rateLimit, authhandler.Login)
+		v1.POST("/login/mfa", rateLimit, mfaHandler.VerifyLogin)
+		v1.POST("/login/magic-link", rateLimit, authhandler.RequestMagicLink)
+		v1.GET("/login/magic-link/verify", rateLimit, authhandler.VerifyMagicLink)
+		v1.POST("/token/refresh", rateLimit, authhandler.RefreshToken)
+		password := v1.Group("/password")
+		{
+			password.POST("/forgot", rateLimit, passwordHandler.ForgotPassword)
+			password.POST("/reset", rateLimit, passwordHandler.ResetPassword)
+		}
+		v1.POST("/logout", authMiddleware, authhandler.Logout)
+		v1.POST("/logout-all", authMiddleware, authhandler.LogoutAll)
+	}
+	oauth := router.Group("/oauth", rateLimit)
+	{
+		oauth.POST("/token", oauthHandler.Token)
+		oauth.POST("/introspect", oauthHandler.Introspect)
+		oauth.POST("/revoke", oauthHandler.Revoke)
 	}
+	router.GET("/.well-known/jwks.json", authhandler.JWKS)
 	router.GET("/docs/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
 	return &Router{
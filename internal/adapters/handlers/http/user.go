@@ -29,6 +29,8 @@ type registerUser struct {
 	PhoneNumber string `json:"phone_number" binding:"required,min=10" example:"9876543210"`
 	FirstName   string `json:"first_name" binding:"required,min=5" example:"Qwerty"`
 	LastName    string `json:"last_name" binding:"required,min=1" example:"A"`
+	Email       string `json:"email" binding:"omitempty,email" example:"example@example.com"`
+	Locale      string `json:"locale" binding:"omitempty,bcp47_language_tag" example:"en"`
 }
 
 //	@Summary		Register a new user
@@ -52,7 +54,12 @@ func (uh *UserHandler) Register(ctx *gin.Context) {
 		PhoneNumber: req.PhoneNumber,
 		FirstName:   req.FirstName,
 		LastName:    req.LastName,
-		Role:        domain.Admin,
+		Role:        domain.Customer,
+		Locale:      req.Locale,
+	}
+	// The email stays unverified until confirmed through the email verification flow
+	if req.Email != "" {
+		user.Email = &req.Email
 	}
 
 	rsp, err := uh.svc.Register(ctx, &user, uh.config)
@@ -70,15 +77,18 @@ type getUserRequest struct {
 }
 
 //	@Summary		Get user by ID
-//	@Description	Retrieves the user from DB based on ID
+//	@Description	Retrieves the user from DB based on ID, only admins can read other users
 //	@Tags			User
 //	@Produce		json
 //	@Accept			json
 //	@Param			id	path		string	true	"Search user by ID"
 //	@Success		200	{object}	response{data=domain.User}
 //	@Failure		400	{object}	response
+//	@Failure		403	{object}	response
 //	@Failure		500	{object}	response
 //	@Router			/users/{id} [get]
+//	@Security		Bearer
+//	@Security		ApiKey
 func (uh *UserHandler) GetUser(ctx *gin.Context) {
 	var req getUserRequest
 	if err := ctx.ShouldBindUri(&req); err != nil {
@@ -86,6 +96,17 @@ func (uh *UserHandler) GetUser(ctx *gin.Context) {
 		return
 	}
 
+	claims, err := getUserClaims(ctx)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	// Reading another user's record requires the read any permission
+	if claims.Subject != req.ID && !claims.HasPermission(domain.PermUserReadAny) {
+		handleError(ctx, domain.ErrForbidden)
+		return
+	}
+
 	rsp, err := uh.svc.GetUser(ctx, req.ID, uh.config)
 	if err != nil {
 		handleError(ctx, err)
@@ -94,3 +115,458 @@ func (uh *UserHandler) GetUser(ctx *gin.Context) {
 
 	handleSuccess(ctx, rsp)
 }
+
+//	@Summary		Unlock user
+//	@Description	Lifts the lockout caused by failed password logins
+//	@Tags			Admin
+//	@Produce		json
+//	@Param			id	path		string	true	"User ID"
+//	@Success		200	{object}	response
+//	@Failure		400	{object}	response
+//	@Failure		403	{object}	response
+//	@Failure		404	{object}	response
+//	@Failure		500	{object}	response
+//	@Router			/admin/users/{id}/unlock [post]
+//	@Security		Bearer
+func (uh *UserHandler) UnlockUser(ctx *gin.Context) {
+	var req getUserRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		validationError(ctx, err)
+		return
+	}
+
+	if err := uh.svc.UnlockUser(ctx, req.ID); err != nil {
+		handleError(ctx, err)
+		return
+	}
+
+	handleSuccess(ctx, nil)
+}
+
+// changeEmail represents the request body for the ChangeEmail endpoint
+type changeEmail struct {
+	Email  string `json:"email" binding:"required,email" example:"example@example.com"`
+	Locale string `json:"locale" binding:"omitempty,bcp47_language_tag" example:"en"`
+}
+
+//	@Summary		Change email
+//	@Description	Sends a verification code to the new email, the email is changed once the code is verified
+//	@Tags			User
+//	@Produce		json
+//	@Accept			json
+//	@Param			email	body		changeEmail	true	"Change Email JSON"
+//	@Success		200		{object}	response
+//	@Failure		400		{object}	response
+//	@Failure		401		{object}	response
+//	@Failure		409		{object}	response
+//	@Failure		500		{object}	response
+//	@Router			/users/me/email [put]
+//	@Security		Bearer
+func (uh *UserHandler) ChangeEmail(ctx *gin.Context) {
+	var req changeEmail
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		validationError(ctx, err)
+		return
+	}
+	claims, err := getUserClaims(ctx)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+
+	if err := uh.svc.ChangeEmail(ctx, claims.Subject, req.Email, req.Locale); err != nil {
+		handleError(ctx, err)
+		return
+	}
+
+	handleSuccess(ctx, nil)
+}
+
+// verifyEmail represents the request body for the VerifyEmail endpoint
+type verifyEmail struct {
+	Code string `json:"code" binding:"required,len=6,numeric" example:"123456"`
+}
+
+//	@Summary		Verify email
+//	@Description	Verifies the code sent to the new email and marks the email verified
+//	@Tags			User
+//	@Produce		json
+//	@Accept			json
+//	@Param			verify	body		verifyEmail	true	"Verify Email JSON"
+//	@Success		200		{object}	response{data=domain.User}
+//	@Failure		400		{object}	response
+//	@Failure		401		{object}	response
+//	@Failure		409		{object}	response
+//	@Failure		429		{object}	response
+//	@Failure		500		{object}	response
+//	@Router			/users/me/email/verify [post]
+//	@Security		Bearer
+func (uh *UserHandler) VerifyEmail(ctx *gin.Context) {
+	var req verifyEmail
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		validationError(ctx, err)
+		return
+	}
+	claims, err := getUserClaims(ctx)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+
+	rsp, err := uh.svc.VerifyEmail(ctx, claims.Subject, req.Code)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+
+	handleSuccess(ctx, rsp)
+}
+
+// changePhoneNumber represents the request body for the ChangePhoneNumber endpoint
+type changePhoneNumber struct {
+	PhoneNumber string `json:"phone_number" binding:"required,min=10" example:"9876543210"`
+	Locale      string `json:"locale" binding:"omitempty,bcp47_language_tag" example:"en"`
+}
+
+//	@Summary		Change phone number
+//	@Description	Sends an OTP to the new phone number, the phone number is changed once the OTP is verified
+//	@Tags			User
+//	@Produce		json
+//	@Accept			json
+//	@Param			phone_number	body		changePhoneNumber	true	"Change Phone Number JSON"
+//	@Success		200				{object}	response
+//	@Failure		400				{object}	response
+//	@Failure		401				{object}	response
+//	@Failure		409				{object}	response
+//	@Failure		500				{object}	response
+//	@Router			/users/me/phone-number [put]
+//	@Security		Bearer
+func (uh *UserHandler) ChangePhoneNumber(ctx *gin.Context) {
+	var req changePhoneNumber
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		validationError(ctx, err)
+		return
+	}
+	claims, err := getUserClaims(ctx)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+
+	if err := uh.svc.ChangePhoneNumber(ctx, claims.Subject, req.PhoneNumber, req.Locale); err != nil {
+		handleError(ctx, err)
+		return
+	}
+
+	handleSuccess(ctx, nil)
+}
+
+// verifyPhoneNumber represents the request body for the VerifyPhoneNumber endpoint
+type verifyPhoneNumber struct {
+	Code string `json:"code" binding:"required,len=6,numeric" example:"123456"`
+}
+
+//	@Summary		Verify phone number
+//	@Description	Verifies the OTP sent to the new phone number and replaces the phone number of the user
+//	@Tags			User
+//	@Produce		json
+//	@Accept			json
+//	@Param			verify	body		verifyPhoneNumber	true	"Verify Phone Number JSON"
+//	@Success		200		{object}	response{data=domain.User}
+//	@Failure		400		{object}	response
+//	@Failure		401		{object}	response
+//	@Failure		409		{object}	response
+//	@Failure		429		{object}	response
+//	@Failure		500		{object}	response
+//	@Router			/users/me/phone-number/verify [post]
+//	@Security		Bearer
+func (uh *UserHandler) VerifyPhoneNumber(ctx *gin.Context) {
+	var req verifyPhoneNumber
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		validationError(ctx, err)
+		return
+	}
+	claims, err := getUserClaims(ctx)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+
+	rsp, err := uh.svc.VerifyPhoneNumber(ctx, claims.Subject, req.Code)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+
+	handleSuccess(ctx, rsp)
+}
+
+// updateProfile represents the request body for the UpdateProfile endpoint, other fields can not be changed
+type updateProfile struct {
+	FirstName   *string `json:"first_name" binding:"omitempty,min=5" example:"Qwerty"`
+	LastName    *string `json:"last_name" binding:"omitempty,min=1" example:"A"`
+	Email       *string `json:"email" binding:"omitempty,email" example:"example@example.com"`
+	PhoneNumber *string `json:"phone_number" binding:"omitempty,min=10" example:"9876543210"`
+}
+
+//	@Summary		Update profile
+//	@Description	Updates the name of the authenticated user, a code is sent to a changed email or phone number which replaces the current one once verified
+//	@Tags			User
+//	@Produce		json
+//	@Accept			json
+//	@Param			profile	body		updateProfile	true	"Update Profile JSON"
+//	@Success		200		{object}	response{data=domain.User}
+//	@Failure		400		{object}	response
+//	@Failure		401		{object}	response
+//	@Failure		409		{object}	response
+//	@Failure		500		{object}	response
+//	@Router			/users/me [patch]
+//	@Security		Bearer
+func (uh *UserHandler) UpdateProfile(ctx *gin.Context) {
+	var req updateProfile
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		validationError(ctx, err)
+		return
+	}
+	claims, err := getUserClaims(ctx)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+
+	patch := domain.UserPatch{
+		FirstName:   req.FirstName,
+		LastName:    req.LastName,
+		Email:       req.Email,
+		PhoneNumber: req.PhoneNumber,
+	}
+	rsp, err := uh.svc.UpdateProfile(ctx, claims.Subject, &patch)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+
+	handleSuccess(ctx, rsp)
+}
+
+// listUsers represents the query parameters for the ListUsers endpoint
+type listUsers struct {
+	Role                  string `form:"role" binding:"omitempty,oneof=ADM RID CUS" example:"CUS"`
+	IsActive              *bool  `form:"is_active"`
+	IsEmailVerified       *bool  `form:"is_email_verified"`
+	IsPhoneNumberVerified *bool  `form:"is_phone_number_verified"`
+	Search                string `form:"search" example:"9876543210"`
+	Cursor                string `form:"cursor" binding:"omitempty,ulid"`
+	Limit                 int    `form:"limit" binding:"omitempty,min=1" example:"20"`
+}
+
+//	@Summary		List users
+//	@Description	Lists users in creation order with cursor pagination, search matches an exact email or phone number
+//	@Tags			Admin
+//	@Produce		json
+//	@Param			role						query		string	false	"Role"	Enums(ADM, RID, CUS)
+//	@Param			is_active					query		bool	false	"Active users"
+//	@Param			is_email_verified			query		bool	false	"Users with verified email"
+//	@Param			is_phone_number_verified	query		bool	false	"Users with verified phone number"
+//	@Param			search						query		string	false	"Exact email or phone number"
+//	@Param			cursor						query		string	false	"Next cursor of the previous page"
+//	@Param			limit						query		int		false	"Page size, larger pages are capped at 100"
+//	@Success		200							{object}	response{data=[]domain.User,meta=paginationMeta}
+//	@Failure		400							{object}	response
+//	@Failure		403							{object}	response
+//	@Failure		500							{object}	response
+//	@Router			/admin/users [get]
+//	@Security		Bearer
+func (uh *UserHandler) ListUsers(ctx *gin.Context) {
+	var req listUsers
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		validationError(ctx, err)
+		return
+	}
+
+	filter := domain.UserFilter{
+		IsActive:              req.IsActive,
+		IsEmailVerified:       req.IsEmailVerified,
+		IsPhoneNumberVerified: req.IsPhoneNumberVerified,
+		Cursor:                req.Cursor,
+		Limit:                 req.Limit,
+	}
+	if req.Role != "" {
+		role := domain.UserRole(req.Role)
+		filter.Role = &role
+	}
+
+	page, err := uh.svc.ListUsers(ctx, &filter, req.Search)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+
+	meta := paginationMeta{
+		Count:      len(page.Users),
+		NextCursor: page.NextCursor,
+		HasMore:    page.NextCursor != "",
+	}
+	handleSuccessWithMeta(ctx, page.Users, meta)
+}
+
+// setRole represents the request body for the SetRole endpoint
+type setRole struct {
+	Role string `json:"role" binding:"required,oneof=ADM RID CUS" example:"RID"`
+}
+
+//	@Summary		Set user role
+//	@Description	Changes the role of a user, the user has to log in again
+//	@Tags			Admin
+//	@Produce		json
+//	@Accept			json
+//	@Param			id		path		string	true	"User ID"
+//	@Param			role	body		setRole	true	"Set Role JSON"
+//	@Success		200		{object}	response{data=domain.User}
+//	@Failure		400		{object}	response
+//	@Failure		403		{object}	response
+//	@Failure		404		{object}	response
+//	@Failure		500		{object}	response
+//	@Router			/admin/users/{id}/role [put]
+//	@Security		Bearer
+func (uh *UserHandler) SetRole(ctx *gin.Context) {
+	var uri getUserRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		validationError(ctx, err)
+		return
+	}
+	var req setRole
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		validationError(ctx, err)
+		return
+	}
+	if err := forbidSelf(ctx, uri.ID); err != nil {
+		handleError(ctx, err)
+		return
+	}
+
+	rsp, err := uh.svc.SetRole(ctx, uri.ID, domain.UserRole(req.Role))
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+
+	handleSuccess(ctx, rsp)
+}
+
+// setStatus represents the request body for the SetStatus endpoint
+type setStatus struct {
+	IsActive *bool `json:"is_active" binding:"required" example:"false"`
+}
+
+//	@Summary		Set user status
+//	@Description	Activates or deactivates a user, deactivated users are logged out and can not log in
+//	@Tags			Admin
+//	@Produce		json
+//	@Accept			json
+//	@Param			id		path		string		true	"User ID"
+//	@Param			status	body		setStatus	true	"Set Status JSON"
+//	@Success		200		{object}	response{data=domain.User}
+//	@Failure		400		{object}	response
+//	@Failure		403		{object}	response
+//	@Failure		404		{object}	response
+//	@Failure		500		{object}	response
+//	@Router			/admin/users/{id}/status [put]
+//	@Security		Bearer
+func (uh *UserHandler) SetStatus(ctx *gin.Context) {
+	var uri getUserRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		validationError(ctx, err)
+		return
+	}
+	var req setStatus
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		validationError(ctx, err)
+		return
+	}
+	if err := forbidSelf(ctx, uri.ID); err != nil {
+		handleError(ctx, err)
+		return
+	}
+
+	rsp, err := uh.svc.SetActive(ctx, uri.ID, *req.IsActive)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+
+	handleSuccess(ctx, rsp)
+}
+
+//	@Summary		Delete account
+//	@Description	Deletes the account of the authenticated user and logs out every session, the personal data is erased after the grace period
+//	@Tags			User
+//	@Produce		json
+//	@Success		200	{object}	response
+//	@Failure		401	{object}	response
+//	@Failure		404	{object}	response
+//	@Failure		500	{object}	response
+//	@Router			/users/me [delete]
+//	@Security		Bearer
+func (uh *UserHandler) DeleteMe(ctx *gin.Context) {
+	claims, err := getUserClaims(ctx)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+
+	if err := uh.svc.DeleteUser(ctx, claims.Subject, claims.Subject); err != nil {
+		handleError(ctx, err)
+		return
+	}
+
+	handleSuccess(ctx, nil)
+}
+
+//	@Summary		Delete user
+//	@Description	Deletes the account of a user and logs out every session, the personal data is erased after the grace period
+//	@Tags			Admin
+//	@Produce		json
+//	@Param			id	path		string	true	"User ID"
+//	@Success		200	{object}	response
+//	@Failure		400	{object}	response
+//	@Failure		403	{object}	response
+//	@Failure		404	{object}	response
+//	@Failure		500	{object}	response
+//	@Router			/admin/users/{id} [delete]
+//	@Security		Bearer
+func (uh *UserHandler) DeleteUser(ctx *gin.Context) {
+	var req getUserRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		validationError(ctx, err)
+		return
+	}
+	claims, err := getUserClaims(ctx)
+	if err != nil {
+		handleError(ctx, err)
+		return
+	}
+	if claims.Subject == req.ID {
+		handleError(ctx, domain.ErrForbidden)
+		return
+	}
+
+	if err := uh.svc.DeleteUser(ctx, req.ID, claims.Subject); err != nil {
+		handleError(ctx, err)
+		return
+	}
+
+	handleSuccess(ctx, nil)
+}
+
+// forbidSelf returns ErrForbidden when the caller is the user, so admins can not lock themselves out
+func forbidSelf(ctx *gin.Context, userID string) error {
+	claims, err := getUserClaims(ctx)
+	if err != nil {
+		return err
+	}
+	if claims.Subject == userID {
+		return domain.ErrForbidden
+	}
+	return nil
+}
@@ -0,0 +1,194 @@
+package keyset
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/arasan1289/hexagonal-demo/internal/adapters/config"
+	"github.com/arasan1289/hexagonal-demo/internal/core/domain"
+	"github.com/arasan1289/hexagonal-demo/internal/core/port"
+)
+
+const (
+	algRS256 = "RS256"
+	algEdDSA = "EdDSA"
+)
+
+/**
+ * KeySet implements port.ITokenKeySet interface
+ * and loads the PEM encoded JWT keys from the file system
+ */
+type KeySet struct {
+	signingKey *domain.TokenKey
+	keys       map[string]*domain.TokenKey
+	jwks       *domain.JWKS
+}
+
+// New loads every configured key, the signing key must have a private key
+func New(conf *config.JWT) (port.ITokenKeySet, error) {
+	ks := &KeySet{
+		keys: make(map[string]*domain.TokenKey),
+		jwks: &domain.JWKS{Keys: []domain.JWK{}},
+	}
+
+	for _, kc := range conf.Keys {
+		if kc.ID == "" {
+			return nil, errors.New("jwt key without id")
+		}
+		if _, ok := ks.keys[kc.ID]; ok {
+			return nil, fmt.Errorf("duplicate jwt key id %q", kc.ID)
+		}
+		key, err := loadKey(kc)
+		if err != nil {
+			return nil, fmt.Errorf("jwt key %q: %w", kc.ID, err)
+		}
+		jwk, err := toJWK(key)
+		if err != nil {
+			return nil, fmt.Errorf("jwt key %q: %w", kc.ID, err)
+		}
+		ks.keys[key.ID] = key
+		ks.jwks.Keys = append(ks.jwks.Keys, *jwk)
+	}
+
+	if conf.SigningKeyID != "" {
+		key, ok := ks.keys[conf.SigningKeyID]
+		if !ok {
+			return nil, fmt.Errorf("signing key %q is not configured", conf.SigningKeyID)
+		}
+		if key.PrivateKey == nil {
+			return nil, fmt.Errorf("signing key %q has no private key", conf.SigningKeyID)
+		}
+		ks.signingKey = key
+	}
+
+	return ks, nil
+}
+
+// SigningKey returns the key new tokens are signed with
+func (ks *KeySet) SigningKey() *domain.TokenKey {
+	return ks.signingKey
+}
+
+// VerificationKey returns the key with the given key ID
+func (ks *KeySet) VerificationKey(kid string) (*domain.TokenKey, error) {
+	key, ok := ks.keys[kid]
+	if !ok {
+		return nil, domain.ErrInvalidToken
+	}
+	return key, nil
+}
+
+// JWKS returns the public keys of all configured keys
+func (ks *KeySet) JWKS() *domain.JWKS {
+	return ks.jwks
+}
+
+// loadKey reads the private and/or public key files of the key
+func loadKey(kc config.JWTKey) (*domain.TokenKey, error) {
+	if kc.Algorithm != algRS256 && kc.Algorithm != algEdDSA {
+		return nil, fmt.Errorf("unsupported algorithm %q", kc.Algorithm)
+	}
+	key := &domain.TokenKey{ID: kc.ID, Algorithm: kc.Algorithm}
+
+	if kc.PrivateKeyFile != "" {
+		block, err := readPEM(kc.PrivateKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		priv, err := parsePrivateKey(block)
+		if err != nil {
+			return nil, err
+		}
+		key.PrivateKey = priv
+		key.PublicKey = priv.Public()
+	}
+
+	if kc.PublicKeyFile != "" {
+		block, err := readPEM(kc.PublicKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		key.PublicKey = pub
+	}
+
+	if key.PublicKey == nil {
+		return nil, errors.New("neither private nor public key file is set")
+	}
+
+	switch key.PublicKey.(type) {
+	case *rsa.PublicKey:
+		if key.Algorithm != algRS256 {
+			return nil, errors.New("RSA key configured for " + key.Algorithm)
+		}
+	case ed25519.PublicKey:
+		if key.Algorithm != algEdDSA {
+			return nil, errors.New("Ed25519 key configured for " + key.Algorithm)
+		}
+	default:
+		return nil, errors.New("unsupported key type")
+	}
+	return key, nil
+}
+
+// readPEM reads the first PEM block of the file
+func readPEM(path string) (*pem.Block, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found in %s", path)
+	}
+	return block, nil
+}
+
+// parsePrivateKey parses a PKCS #8 or PKCS #1 private key
+func parsePrivateKey(block *pem.Block) (crypto.Signer, error) {
+	if block.Type == "RSA PRIVATE KEY" {
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("unsupported private key type")
+	}
+	return signer, nil
+}
+
+// toJWK converts the public part of the key into a JWK
+func toJWK(key *domain.TokenKey) (*domain.JWK, error) {
+	jwk := &domain.JWK{
+		KeyID:     key.ID,
+		Use:       "sig",
+		Algorithm: key.Algorithm,
+	}
+	switch pub := key.PublicKey.(type) {
+	case *rsa.PublicKey:
+		jwk.KeyType = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.KeyType = "OKP"
+		jwk.Curve = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+	default:
+		return nil, errors.New("unsupported key type")
+	}
+	return jwk, nil
+}
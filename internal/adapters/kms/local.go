@@ -0,0 +1,126 @@
+package kms
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/arasan1289/hexagonal-demo/internal/adapters/config"
+	"github.com/arasan1289/hexagonal-demo/internal/core/port"
+)
+
+const dataKeySize = 32
+
+/**
+ * LocalKMS implements port.IKeyManager interface
+ * with versioned master keys loaded from a local JSON file
+ */
+type LocalKMS struct {
+	current    uint
+	masterKeys map[uint]cipher.AEAD
+}
+
+// New loads the master keys from the key file, a JSON object of versions to hex encoded 32 byte keys
+// such as {"1": "<64 hex characters>"}. Data keys are wrapped with the current version, the other
+// versions are only used to unwrap data keys wrapped before a rotation.
+func New(conf *config.KMS) (port.IKeyManager, error) {
+	data, err := os.ReadFile(conf.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+	var keys map[string]string
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return nil, fmt.Errorf("kms key file: %w", err)
+	}
+
+	kms := &LocalKMS{
+		current:    conf.CurrentVersion,
+		masterKeys: make(map[uint]cipher.AEAD),
+	}
+	for v, k := range keys {
+		version, err := strconv.ParseUint(v, 10, 32)
+		if err != nil || version == 0 {
+			return nil, fmt.Errorf("kms master key version %q is not a positive number", v)
+		}
+		key, err := hex.DecodeString(k)
+		if err != nil || len(key) != dataKeySize {
+			return nil, fmt.Errorf("kms master key %q is not a hex encoded 32 byte key", v)
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		gcm, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		kms.masterKeys[uint(version)] = gcm
+	}
+	if _, ok := kms.masterKeys[kms.current]; !ok {
+		return nil, fmt.Errorf("kms current master key version %d is not configured", kms.current)
+	}
+	return kms, nil
+}
+
+// GenerateDataKey generates a random data key and wraps it with the current master key
+func (k *LocalKMS) GenerateDataKey(ctx context.Context) ([]byte, string, error) {
+	dataKey := make([]byte, dataKeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, "", err
+	}
+	wrapped, err := k.WrapDataKey(ctx, dataKey)
+	if err != nil {
+		return nil, "", err
+	}
+	return dataKey, wrapped, nil
+}
+
+// WrapDataKey encrypts the data key with the current master key and prefixes it with the master key version
+func (k *LocalKMS) WrapDataKey(ctx context.Context, dataKey []byte) (string, error) {
+	gcm := k.masterKeys[k.current]
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	wrapped := gcm.Seal(nonce, nonce, dataKey, nil)
+	return k.CurrentKeyPrefix() + hex.EncodeToString(wrapped), nil
+}
+
+// CurrentKeyPrefix returns the "v<version>:" prefix of the current master key version
+func (k *LocalKMS) CurrentKeyPrefix() string {
+	return fmt.Sprintf("v%d:", k.current)
+}
+
+// UnwrapDataKey unwraps the data key with the master key version of its "v<version>:" prefix
+func (k *LocalKMS) UnwrapDataKey(ctx context.Context, wrapped string) ([]byte, error) {
+	v, encKey, ok := strings.Cut(strings.TrimPrefix(wrapped, "v"), ":")
+	if !ok {
+		return nil, errors.New("wrapped data key has no master key version")
+	}
+	version, err := strconv.ParseUint(v, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("wrapped data key has an invalid master key version %q", v)
+	}
+	gcm, ok := k.masterKeys[uint(version)]
+	if !ok {
+		return nil, fmt.Errorf("kms master key version %d is not configured", version)
+	}
+
+	data, err := hex.DecodeString(encKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, errors.New("wrapped data key is too short")
+	}
+	nonce, data := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, data, nil)
+}
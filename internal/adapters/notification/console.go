@@ -0,0 +1,48 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/arasan1289/hexagonal-demo/internal/core/domain"
+)
+
+// Console writes every notification as a JSON line, meant for development
+type Console struct {
+	mu  sync.Mutex
+	out io.Writer
+}
+
+// consoleEntry is a single notification written by the console driver
+type consoleEntry struct {
+	Time    time.Time                  `json:"time"`
+	Channel domain.NotificationChannel `json:"channel"`
+	To      string                     `json:"to"`
+	*Message
+}
+
+// NewConsole creates a new instance of Console writing to out
+func NewConsole(out io.Writer) *Console {
+	return &Console{out: out}
+}
+
+// Send writes the message to the output
+func (c *Console) Send(ctx context.Context, channel domain.NotificationChannel, to string, msg *Message) error {
+	line, err := json.Marshal(consoleEntry{
+		Time:    time.Now(),
+		Channel: channel,
+		To:      to,
+		Message: msg,
+	})
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, err = c.out.Write(append(line, '\n'))
+	return err
+}
@@ -0,0 +1,123 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/arasan1289/hexagonal-demo/internal/adapters/config"
+	"github.com/arasan1289/hexagonal-demo/internal/core/domain"
+	"github.com/arasan1289/hexagonal-demo/internal/core/port"
+)
+
+const (
+	driverConsole = "console"
+	driverFile    = "file"
+	driverWebhook = "webhook"
+
+	defaultWebhookTimeout = time.Second * 10
+)
+
+// transport delivers a rendered message to the recipient
+type transport interface {
+	Send(ctx context.Context, channel domain.NotificationChannel, to string, msg *Message) error
+}
+
+/**
+ * Notifier implements port.INotificationService interface,
+ * it renders the templates and dispatches every channel to its configured driver
+ */
+type Notifier struct {
+	renderer   *Renderer
+	transports map[domain.NotificationChannel]transport
+}
+
+// New creates the drivers configured for each channel, channels without a driver are written to the console
+func New(conf *config.Notification) (port.INotificationService, error) {
+	renderer, err := NewRenderer()
+	if err != nil {
+		return nil, err
+	}
+
+	drivers := make(map[string]transport)
+	driver := func(name string) (transport, error) {
+		if name == "" {
+			name = driverConsole
+		}
+		if d, ok := drivers[name]; ok {
+			return d, nil
+		}
+		var d transport
+		switch name {
+		case driverConsole:
+			d = NewConsole(os.Stdout)
+		case driverFile:
+			f, err := os.OpenFile(conf.FilePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+			if err != nil {
+				return nil, err
+			}
+			d = NewConsole(f)
+		case driverWebhook:
+			timeout := defaultWebhookTimeout
+			if conf.WebhookTimeout != 0 {
+				timeout = time.Duration(conf.WebhookTimeout) * time.Second
+			}
+			d = NewWebhook(conf.WebhookURL, conf.WebhookToken, timeout)
+		default:
+			return nil, fmt.Errorf("unknown notification driver: %s", name)
+		}
+		drivers[name] = d
+		return d, nil
+	}
+
+	n := &Notifier{
+		renderer:   renderer,
+		transports: make(map[domain.NotificationChannel]transport),
+	}
+	channels := map[domain.NotificationChannel]string{
+		domain.ChannelSMS:      conf.SMS,
+		domain.ChannelWhatsapp: conf.Whatsapp,
+		domain.ChannelEmail:    conf.Email,
+		domain.ChannelPush:     conf.Push,
+	}
+	for channel, name := range channels {
+		d, err := driver(name)
+		if err != nil {
+			return nil, err
+		}
+		n.transports[channel] = d
+	}
+	return n, nil
+}
+
+// SendSMS renders the template and sends it as SMS
+func (n *Notifier) SendSMS(ctx context.Context, to string, template domain.TemplateID, locale string, data map[string]any) (bool, error) {
+	return n.send(ctx, domain.ChannelSMS, to, template, locale, data)
+}
+
+// SendEmail renders the template and sends it as email
+func (n *Notifier) SendEmail(ctx context.Context, to string, template domain.TemplateID, locale string, data map[string]any) (bool, error) {
+	return n.send(ctx, domain.ChannelEmail, to, template, locale, data)
+}
+
+// SendPush renders the template and sends it as push notification
+func (n *Notifier) SendPush(ctx context.Context, to string, template domain.TemplateID, locale string, data map[string]any) (bool, error) {
+	return n.send(ctx, domain.ChannelPush, to, template, locale, data)
+}
+
+// SendWhatsapp renders the template and sends it as Whatsapp message
+func (n *Notifier) SendWhatsapp(ctx context.Context, to string, template domain.TemplateID, locale string, data map[string]any) (bool, error) {
+	return n.send(ctx, domain.ChannelWhatsapp, to, template, locale, data)
+}
+
+func (n *Notifier) send(ctx context.Context, channel domain.NotificationChannel, to string, template domain.TemplateID, locale string, data map[string]any) (bool, error) {
+	msg, err := n.renderer.Render(template, locale, channel, data)
+	if err != nil {
+		return false, err
+	}
+	if err := n.transports[channel].Send(ctx, channel, to, msg); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,147 @@
+package notification
+
+import (
+	"bytes"
+	"embed"
+	htemplate "html/template"
+	"io/fs"
+	"path"
+	"strings"
+	ttemplate "text/template"
+
+	"github.com/arasan1289/hexagonal-demo/internal/core/domain"
+)
+
+//go:embed templates
+var templateFS embed.FS
+
+// Template files are stored as templates/<template id>/<locale>.<kind>
+const (
+	kindText    = ".txt"     // Body of SMS, Whatsapp and push messages, plain text email fallback
+	kindHTML    = ".html"    // Email body
+	kindSubject = ".subject" // Email subject
+)
+
+// Message is a rendered notification
+type Message struct {
+	Subject string `json:"subject,omitempty"`
+	Body    string `json:"message"`
+	HTML    bool   `json:"html,omitempty"`
+}
+
+// Renderer is the registry of the embedded notification templates
+type Renderer struct {
+	text map[string]*ttemplate.Template
+	html map[string]*htemplate.Template
+}
+
+// NewRenderer parses every embedded template
+func NewRenderer() (*Renderer, error) {
+	r := &Renderer{
+		text: make(map[string]*ttemplate.Template),
+		html: make(map[string]*htemplate.Template),
+	}
+	err := fs.WalkDir(templateFS, "templates", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		src, err := templateFS.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		name := strings.TrimPrefix(p, "templates/")
+		if path.Ext(p) == kindHTML {
+			t, err := htemplate.New(name).Option("missingkey=error").Parse(string(src))
+			if err != nil {
+				return err
+			}
+			r.html[name] = t
+			return nil
+		}
+		t, err := ttemplate.New(name).Option("missingkey=error").Parse(strings.TrimSpace(string(src)))
+		if err != nil {
+			return err
+		}
+		r.text[name] = t
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Render renders the template for the channel in the first locale having a variant:
+// the given locale, its base language and finally domain.DefaultLocale
+func (r *Renderer) Render(id domain.TemplateID, locale string, channel domain.NotificationChannel, data map[string]any) (*Message, error) {
+	if err := domain.ValidateTemplateData(id, data); err != nil {
+		return nil, err
+	}
+
+	for _, l := range localeCandidates(locale) {
+		base := string(id) + "/" + l
+		if channel == domain.ChannelEmail {
+			if msg, ok, err := r.renderEmail(base, data); ok || err != nil {
+				return msg, err
+			}
+			continue
+		}
+		if t, ok := r.text[base+kindText]; ok {
+			body, err := executeText(t, data)
+			if err != nil {
+				return nil, err
+			}
+			return &Message{Body: body}, nil
+		}
+	}
+	return nil, domain.ErrUnknownTemplate
+}
+
+// renderEmail renders the HTML body, or the plain text one, together with the subject of the locale
+func (r *Renderer) renderEmail(base string, data map[string]any) (*Message, bool, error) {
+	st, ok := r.text[base+kindSubject]
+	if !ok {
+		return nil, false, nil
+	}
+	subject, err := executeText(st, data)
+	if err != nil {
+		return nil, true, err
+	}
+
+	if ht, ok := r.html[base+kindHTML]; ok {
+		var buf bytes.Buffer
+		if err := ht.Execute(&buf, data); err != nil {
+			return nil, true, err
+		}
+		return &Message{Subject: subject, Body: buf.String(), HTML: true}, true, nil
+	}
+	if tt, ok := r.text[base+kindText]; ok {
+		body, err := executeText(tt, data)
+		if err != nil {
+			return nil, true, err
+		}
+		return &Message{Subject: subject, Body: body}, true, nil
+	}
+	return nil, false, nil
+}
+
+func executeText(t *ttemplate.Template, data map[string]any) (string, error) {
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// localeCandidates lists the locales to try in order, e.g. ta-IN, ta, en
+func localeCandidates(locale string) []string {
+	locale = strings.ToLower(strings.ReplaceAll(locale, "_", "-"))
+	candidates := make([]string, 0, 3)
+	if locale != "" {
+		candidates = append(candidates, locale)
+		if lang, _, ok := strings.Cut(locale, "-"); ok {
+			candidates = append(candidates, lang)
+		}
+	}
+	return append(candidates, domain.DefaultLocale)
+}
@@ -0,0 +1,71 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/arasan1289/hexagonal-demo/internal/core/domain"
+)
+
+// Webhook posts every notification to an HTTP endpoint of the messaging provider
+type Webhook struct {
+	client *http.Client
+	url    string
+	token  string
+}
+
+// webhookPayload is the request body posted to the webhook
+type webhookPayload struct {
+	Channel domain.NotificationChannel `json:"channel"`
+	To      string                     `json:"to"`
+	*Message
+}
+
+// NewWebhook creates a new instance of Webhook posting to url, token is sent as bearer token when set
+func NewWebhook(url, token string, timeout time.Duration) *Webhook {
+	return &Webhook{
+		client: &http.Client{Timeout: timeout},
+		url:    url,
+		token:  token,
+	}
+}
+
+// Send posts the message to the webhook
+func (w *Webhook) Send(ctx context.Context, channel domain.NotificationChannel, to string, msg *Message) error {
+	body, err := json.Marshal(webhookPayload{
+		Channel: channel,
+		To:      to,
+		Message: msg,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if w.token != "" {
+		req.Header.Set("Authorization", "Bearer "+w.token)
+	}
+
+	rsp, err := w.client.Do(req)
+	if err != nil {
+		// Timeouts and unreachable webhooks are delivery failures as well
+		return fmt.Errorf("%w: %v", domain.ErrNotificationFailed, err)
+	}
+	defer rsp.Body.Close()
+	// Drain the body so the connection can be reused
+	_, _ = io.Copy(io.Discard, rsp.Body)
+
+	if rsp.StatusCode < 200 || rsp.StatusCode >= 300 {
+		return fmt.Errorf("%w: status %d", domain.ErrNotificationFailed, rsp.StatusCode)
+	}
+	return nil
+}
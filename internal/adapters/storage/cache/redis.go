@@ -2,13 +2,33 @@ package redis
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"github.com/arasan1289/hexagonal-demo/internal/adapters/config"
+	"github.com/arasan1289/hexagonal-demo/internal/core/domain"
 	"github.com/arasan1289/hexagonal-demo/internal/core/port"
 	"github.com/redis/go-redis/v9"
 )
 
+// compareAndSwapScript sets KEYS[1] to ARGV[2] with a TTL of ARGV[3] milliseconds if it still holds ARGV[1]
+var compareAndSwapScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	redis.call("SET", KEYS[1], ARGV[2], "PX", ARGV[3])
+	return 1
+end
+return 0
+`)
+
+// incrementScript increments KEYS[1] and sets its TTL to ARGV[1] milliseconds when it was created by the increment
+var incrementScript = redis.NewScript(`
+local n = redis.call("INCR", KEYS[1])
+if n == 1 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+end
+return n
+`)
+
 /**
  * Redis implements port.ICache interface
  * and provides an access to the redis library
@@ -38,13 +58,41 @@ func (r *Redis) Set(ctx context.Context, key string, value []byte, ttl time.Dura
 	return r.client.Set(ctx, key, value, ttl).Err()
 }
 
-// Get retrieves the value from the redis database
+// Get retrieves the value from the redis database, a missing key is reported as domain.ErrDataNotFound
 func (r *Redis) Get(ctx context.Context, key string) ([]byte, error) {
 	res, err := r.client.Get(ctx, key).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, domain.ErrDataNotFound
+	}
 	bytes := []byte(res)
 	return bytes, err
 }
 
+// CompareAndSwap replaces the value in the redis database if it still equals old, in one script so no
+// other client can change the value in between
+func (r *Redis) CompareAndSwap(ctx context.Context, key string, old, new []byte, ttl time.Duration) (bool, error) {
+	swapped, err := compareAndSwapScript.Run(ctx, r.client, []string{key}, old, new, ttl.Milliseconds()).Int()
+	if err != nil {
+		return false, err
+	}
+	return swapped == 1, nil
+}
+
+// GetDelete retrieves and removes the value from the redis database, a missing key is reported as domain.ErrDataNotFound
+func (r *Redis) GetDelete(ctx context.Context, key string) ([]byte, error) {
+	res, err := r.client.GetDel(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, domain.ErrDataNotFound
+	}
+	return res, err
+}
+
+// Increment increments the counter in the redis database, the TTL is only set when the counter is created
+// so it expires after the first increment rather than the last
+func (r *Redis) Increment(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	return incrementScript.Run(ctx, r.client, []string{key}, ttl.Milliseconds()).Int64()
+}
+
 // Delete removes the value from the redis database
 func (r *Redis) Delete(ctx context.Context, key string) error {
 	return r.client.Del(ctx, key).Err()
@@ -57,7 +105,7 @@ func (r *Redis) DeleteByPrefix(ctx context.Context, prefix string) error {
 
 	for {
 		var err error
-		keys, cursor, err = r.client.Scan(ctx, cursor, prefix, 100).Result()
+		keys, cursor, err = r.client.Scan(ctx, cursor, prefix+"*", 100).Result()
 		if err != nil {
 			return err
 		}
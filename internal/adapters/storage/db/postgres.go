@@ -9,6 +9,7 @@ import (
 	"gorm.io/gorm/logger"
 
 	"github.com/arasan1289/hexagonal-demo/internal/adapters/config"
+	"github.com/arasan1289/hexagonal-demo/internal/core/port"
 )
 
 // Conn implements the DB interface using GORM.
@@ -42,6 +43,11 @@ func New(config *config.Container, logger logger.Interface) (*Conn, error) {
 	return &Conn{db, connectionString, config}, nil
 }
 
+// SetKeyManager makes the key manager available to the AfterFind hooks decrypting personal data.
+func (c *Conn) SetKeyManager(keys port.IKeyManager) {
+	c.DB = c.DB.InstanceSet("keys", keys)
+}
+
 // Set sets the connection pool.
 func (c *Conn) Set() error {
 	db, err := c.DB.DB()
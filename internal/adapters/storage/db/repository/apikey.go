@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	postgres "github.com/arasan1289/hexagonal-demo/internal/adapters/storage/db"
+	"github.com/arasan1289/hexagonal-demo/internal/core/domain"
+	"github.com/arasan1289/hexagonal-demo/internal/core/port"
+	"gorm.io/gorm"
+)
+
+// APIKeyRepository is an implementation of the port.IAPIKeyRepository interface using a PostgreSQL database.
+type APIKeyRepository struct {
+	db *postgres.Conn
+}
+
+// NewAPIKeyRepository creates a new instance of APIKeyRepository with the provided database connection.
+func NewAPIKeyRepository(conn *postgres.Conn) port.IAPIKeyRepository {
+	return &APIKeyRepository{
+		db: conn,
+	}
+}
+
+// CreateAPIKey inserts the API key.
+func (ar *APIKeyRepository) CreateAPIKey(ctx context.Context, key *domain.APIKey) error {
+	return ar.db.WithContext(ctx).Create(key).Error
+}
+
+// GetAPIKeyByHash retrieves an API key by the hash of the key.
+func (ar *APIKeyRepository) GetAPIKeyByHash(ctx context.Context, hash string) (*domain.APIKey, error) {
+	var key domain.APIKey
+	err := ar.db.WithContext(ctx).Where("key_hash = ?", hash).First(&key).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, domain.ErrDataNotFound
+		}
+		return nil, err
+	}
+	return &key, nil
+}
+
+// ListAPIKeys retrieves all API keys, ULID IDs sort them by creation time.
+func (ar *APIKeyRepository) ListAPIKeys(ctx context.Context) ([]domain.APIKey, error) {
+	var keys []domain.APIKey
+	if err := ar.db.WithContext(ctx).Order("id DESC").Find(&keys).Error; err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// UpdateAPIKey updates only the given columns of the API key identified by its ID.
+func (ar *APIKeyRepository) UpdateAPIKey(ctx context.Context, key *domain.APIKey, columns ...string) error {
+	result := ar.db.WithContext(ctx).Model(key).Select(columns).Updates(key)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domain.ErrDataNotFound
+	}
+	return nil
+}
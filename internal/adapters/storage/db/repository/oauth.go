@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	postgres "github.com/arasan1289/hexagonal-demo/internal/adapters/storage/db"
+	"github.com/arasan1289/hexagonal-demo/internal/core/domain"
+	"github.com/arasan1289/hexagonal-demo/internal/core/port"
+	"gorm.io/gorm"
+)
+
+// OAuthClientRepository is an implementation of the port.IOAuthClientRepository interface using a PostgreSQL database.
+type OAuthClientRepository struct {
+	db *postgres.Conn
+}
+
+// NewOAuthClientRepository creates a new instance of OAuthClientRepository with the provided database connection.
+func NewOAuthClientRepository(conn *postgres.Conn) port.IOAuthClientRepository {
+	return &OAuthClientRepository{
+		db: conn,
+	}
+}
+
+// CreateClient inserts the OAuth client.
+func (or *OAuthClientRepository) CreateClient(ctx context.Context, client *domain.OAuthClient) error {
+	return or.db.WithContext(ctx).Create(client).Error
+}
+
+// GetClient retrieves an OAuth client by its ID.
+func (or *OAuthClientRepository) GetClient(ctx context.Context, id string) (*domain.OAuthClient, error) {
+	var client domain.OAuthClient
+	err := or.db.WithContext(ctx).Where("id = ?", id).First(&client).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, domain.ErrDataNotFound
+		}
+		return nil, err
+	}
+	return &client, nil
+}
+
+// UpdateClient updates only the given columns of the OAuth client identified by its ID.
+func (or *OAuthClientRepository) UpdateClient(ctx context.Context, client *domain.OAuthClient, columns ...string) error {
+	result := or.db.WithContext(ctx).Model(client).Select(columns).Updates(client)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domain.ErrDataNotFound
+	}
+	return nil
+}
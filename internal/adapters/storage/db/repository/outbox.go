@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	postgres "github.com/arasan1289/hexagonal-demo/internal/adapters/storage/db"
+	"github.com/arasan1289/hexagonal-demo/internal/core/domain"
+	"github.com/arasan1289/hexagonal-demo/internal/core/port"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// OutboxRepository is an implementation of the port.IOutboxRepository interface using a PostgreSQL database.
+type OutboxRepository struct {
+	db *postgres.Conn
+}
+
+// NewOutboxRepository creates a new instance of OutboxRepository with the provided database connection.
+func NewOutboxRepository(conn *postgres.Conn) port.IOutboxRepository {
+	return &OutboxRepository{
+		db: conn,
+	}
+}
+
+// CreateMessages inserts the outbox messages.
+func (or *OutboxRepository) CreateMessages(ctx context.Context, msgs ...*domain.OutboxMessage) error {
+	return or.db.WithContext(ctx).Create(msgs).Error
+}
+
+// ClaimPending selects the due messages with SELECT ... FOR UPDATE SKIP LOCKED, so concurrent dispatchers never
+// claim the same message, marks them in flight and counts the attempt. The transaction only spans the claim, the messages are
+// published without holding row locks.
+func (or *OutboxRepository) ClaimPending(ctx context.Context, leaseID string, leasedUntil time.Time, limit int) ([]domain.OutboxMessage, error) {
+	var msgs []domain.OutboxMessage
+	err := or.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status IN ? AND next_attempt_at <= ?", []domain.OutboxStatus{domain.OutboxPending, domain.OutboxInFlight}, time.Now()).
+			Order("next_attempt_at").
+			Limit(limit).
+			Find(&msgs).Error
+		if err != nil || len(msgs) == 0 {
+			return err
+		}
+
+		ids := make([]string, len(msgs))
+		for i := range msgs {
+			msgs[i].Status = domain.OutboxInFlight
+			msgs[i].Attempts++
+			msgs[i].LeaseID = leaseID
+			msgs[i].NextAttemptAt = leasedUntil
+			ids[i] = msgs[i].ID
+		}
+		return tx.Model(&domain.OutboxMessage{}).Where("id IN ?", ids).Updates(map[string]any{
+			"status":          domain.OutboxInFlight,
+			"attempts":        gorm.Expr("attempts + 1"),
+			"lease_id":        leaseID,
+			"next_attempt_at": leasedUntil,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return msgs, nil
+}
+
+// CompleteMessage saves the delivery state of the message as long as it is still claimed under the same lease.
+func (or *OutboxRepository) CompleteMessage(ctx context.Context, msg *domain.OutboxMessage) error {
+	result := or.db.WithContext(ctx).Model(&domain.OutboxMessage{}).
+		Where("id = ? AND status = ? AND lease_id = ?", msg.ID, domain.OutboxInFlight, msg.LeaseID).
+		Updates(map[string]any{
+			"status":          msg.Status,
+			"attempts":        msg.Attempts,
+			"next_attempt_at": msg.NextAttemptAt,
+			"last_error":      msg.LastError,
+			"lease_id":        "",
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domain.ErrDataNotFound
+	}
+	return nil
+}
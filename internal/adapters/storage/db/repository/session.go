@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	postgres "github.com/arasan1289/hexagonal-demo/internal/adapters/storage/db"
+	"github.com/arasan1289/hexagonal-demo/internal/core/domain"
+	"github.com/arasan1289/hexagonal-demo/internal/core/port"
+)
+
+// SessionRepository is an implementation of the port.ISessionRepository interface using a PostgreSQL database.
+type SessionRepository struct {
+	db *postgres.Conn
+}
+
+// NewSessionRepository creates a new instance of SessionRepository with the provided database connection.
+func NewSessionRepository(conn *postgres.Conn) port.ISessionRepository {
+	return &SessionRepository{
+		db: conn,
+	}
+}
+
+// CreateSession inserts the session.
+func (sr *SessionRepository) CreateSession(ctx context.Context, session *domain.Session) error {
+	return sr.db.WithContext(ctx).Create(session).Error
+}
+
+// TouchSession sets the last seen time of the session.
+func (sr *SessionRepository) TouchSession(ctx context.Context, id string, lastSeenAt time.Time) error {
+	return sr.db.WithContext(ctx).Model(&domain.Session{}).Where("id = ?", id).Update("last_seen_at", lastSeenAt).Error
+}
+
+// ListSessions retrieves the sessions of the user ordered by the last seen time.
+func (sr *SessionRepository) ListSessions(ctx context.Context, userID string) ([]domain.Session, error) {
+	var sessions []domain.Session
+	err := sr.db.WithContext(ctx).Where("user_id = ?", userID).Order("last_seen_at DESC").Find(&sessions).Error
+	if err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// DeleteSession deletes the session when it belongs to the user.
+func (sr *SessionRepository) DeleteSession(ctx context.Context, userID, id string) error {
+	result := sr.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).Delete(&domain.Session{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domain.ErrDataNotFound
+	}
+	return nil
+}
+
+// DeleteUserSessions deletes all sessions of the user.
+func (sr *SessionRepository) DeleteUserSessions(ctx context.Context, userID string) error {
+	return sr.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&domain.Session{}).Error
+}
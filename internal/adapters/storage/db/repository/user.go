@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"errors"
+	"time"
 
 	postgres "github.com/arasan1289/hexagonal-demo/internal/adapters/storage/db"
 	"github.com/arasan1289/hexagonal-demo/internal/core/domain"
@@ -25,48 +26,309 @@ func NewUserRepository(conn *postgres.Conn) port.IUserRepository {
 
 // UpsertUser creates or updates a user in the database.
 // It uses the OnConflict clause to update all fields if the user already exists.
-func (ur *UserRepository) UpsertUser(ctx context.Context, user *domain.User) (*domain.User, error) {
-	data := ur.db.Clauses(clause.OnConflict{UpdateAll: true}, clause.Returning{}).Create(user)
-	if data.Error != nil {
-		return nil, data.Error
+// The outbox messages are written in the same transaction.
+func (ur *UserRepository) UpsertUser(ctx context.Context, user *domain.User, outbox ...*domain.OutboxMessage) (*domain.User, error) {
+	err := ur.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{UpdateAll: true}, clause.Returning{}).Create(user).Error; err != nil {
+			return err
+		}
+		if len(outbox) > 0 {
+			return tx.Create(outbox).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// UpdateUser updates only the given columns of the user identified by its ID.
+func (ur *UserRepository) UpdateUser(ctx context.Context, user *domain.User, columns ...string) (*domain.User, error) {
+	result := ur.db.Model(user).Select(columns).Updates(user)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, domain.ErrDataNotFound
 	}
 	return user, nil
 }
 
-// GetUser retrieves a user from the database by their ID.
+// GetUser retrieves a user from the database by their ID, soft deleted users are not found.
 func (ur *UserRepository) GetUser(ctx context.Context, id string) (*domain.User, error) {
 	var user domain.User
 	var result *gorm.DB
 	ur.db.Transaction(func(tx *gorm.DB) error {
-		c, ok := ur.db.InstanceGet("config")
-		if !ok {
-			return errors.New("config not found")
+		tx, err := ur.withKeys(tx)
+		if err != nil {
+			return err
 		}
-		tx = tx.InstanceSet("config", c)
-		result = tx.First(&user, "id=?", id)
+		result = tx.First(&user, "id=? AND deleted_at IS NULL", id)
 		return nil
 	})
 	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, domain.ErrDataNotFound
+		}
 		return nil, result.Error
 	}
 	return &user, nil
 }
 
-// GetUserByPhoneNumber retrieves a user from the database by their phone number hash or email hash.
-func (ur *UserRepository) GetUserByPhoneNumberOrEmail(ctx context.Context, hash string) (*domain.User, error) {
+// GetUserByPhoneNumber retrieves a user from the database by any of the given phone number hashes or email hashes.
+// An email may be entered by several users until one of them verifies it, the verified owner is returned first.
+func (ur *UserRepository) GetUserByPhoneNumberOrEmail(ctx context.Context, hashes ...string) (*domain.User, error) {
 	var user domain.User
 	var result *gorm.DB
 	ur.db.Transaction(func(tx *gorm.DB) error {
-		c, ok := ur.db.InstanceGet("config")
-		if !ok {
-			return errors.New("config not found")
+		tx, err := ur.withKeys(tx)
+		if err != nil {
+			return err
 		}
-		tx = tx.InstanceSet("config", c)
-		result = tx.Where("phone_number_hash=?", hash).Or("email_hash=?", hash).Take(&user)
+		result = tx.Where("(phone_number_hash IN ? OR email_hash IN ?) AND deleted_at IS NULL", hashes, hashes).
+			Order("is_email_verified DESC").
+			Take(&user)
 		return nil
 	})
 	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, domain.ErrDataNotFound
+		}
 		return nil, result.Error
 	}
 	return &user, nil
 }
+
+// ClearUnverifiedEmails removes the email from the other users who entered it without verifying it.
+func (ur *UserRepository) ClearUnverifiedEmails(ctx context.Context, userID string, hashes ...string) error {
+	return ur.db.WithContext(ctx).Model(&domain.User{}).
+		Where("email_hash IN ? AND is_email_verified = ? AND id <> ? AND deleted_at IS NULL", hashes, false, userID).
+		Updates(map[string]any{"email_hash": nil, "email_encrypted": nil}).Error
+}
+
+// ListUsers retrieves the users matching the filter ordered by ID, ULIDs sort by creation time.
+// Soft deleted users are not listed.
+func (ur *UserRepository) ListUsers(ctx context.Context, filter *domain.UserFilter) ([]domain.User, error) {
+	var users []domain.User
+	var result *gorm.DB
+	ur.db.Transaction(func(tx *gorm.DB) error {
+		tx, err := ur.withKeys(tx)
+		if err != nil {
+			return err
+		}
+		tx = tx.Where("deleted_at IS NULL")
+		if filter.Cursor != "" {
+			tx = tx.Where("id > ?", filter.Cursor)
+		}
+		if filter.Role != nil {
+			tx = tx.Where("role = ?", *filter.Role)
+		}
+		if filter.IsActive != nil {
+			tx = tx.Where("is_active = ?", *filter.IsActive)
+		}
+		if filter.IsEmailVerified != nil {
+			tx = tx.Where("is_email_verified = ?", *filter.IsEmailVerified)
+		}
+		if filter.IsPhoneNumberVerified != nil {
+			tx = tx.Where("is_phone_number_verified = ?", *filter.IsPhoneNumberVerified)
+		}
+		if len(filter.IdentifierHashes) > 0 {
+			tx = tx.Where("(phone_number_hash IN ? OR email_hash IN ?)", filter.IdentifierHashes, filter.IdentifierHashes)
+		}
+		result = tx.Order("id").Limit(filter.Limit).Find(&users)
+		return nil
+	})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return users, nil
+}
+
+// ListUsersAfter retrieves the users after the given ID ordered by ID. Every query reads one batch by primary key
+// without locking rows, so walking the table does not block the application.
+func (ur *UserRepository) ListUsersAfter(ctx context.Context, after string, limit int) ([]domain.User, error) {
+	var users []domain.User
+	err := ur.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		tx, err := ur.withKeys(tx)
+		if err != nil {
+			return err
+		}
+		return tx.Where("id > ?", after).Order("id").Limit(limit).Find(&users).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// SetDataKey sets the data key only where it is NULL, then reads the data key back, so a key stored by a concurrent
+// request is never overwritten and every caller gets the stored key.
+func (ur *UserRepository) SetDataKey(ctx context.Context, id, wrapped string) (string, error) {
+	db := ur.db.WithContext(ctx)
+	err := db.Model(&domain.User{}).
+		Where("id = ? AND data_key IS NULL", id).
+		UpdateColumn("data_key", wrapped).Error
+	if err != nil {
+		return "", err
+	}
+	var dataKeys []string
+	err = db.Model(&domain.User{}).Where("id = ? AND data_key IS NOT NULL", id).Pluck("data_key", &dataKeys).Error
+	if err != nil {
+		return "", err
+	}
+	if len(dataKeys) == 0 {
+		return "", domain.ErrDataNotFound
+	}
+	return dataKeys[0], nil
+}
+
+// SwapUserCiphertexts updates the encrypted and blind index columns only if none of them changed since they were read,
+// so concurrent updates by the application are never overwritten with stale data. The update time is kept.
+func (ur *UserRepository) SwapUserCiphertexts(ctx context.Context, id string, old, new *domain.UserCiphertexts) (bool, error) {
+	result := ur.db.WithContext(ctx).Model(&domain.User{}).
+		Where("id = ?", id).
+		Where("data_key IS NOT DISTINCT FROM ?", old.DataKey).
+		Where("phone_number_encrypted IS NOT DISTINCT FROM ?", old.PhoneNumberEncrypted).
+		Where("phone_number_hash IS NOT DISTINCT FROM ?", old.PhoneNumberHash).
+		Where("email_encrypted IS NOT DISTINCT FROM ?", old.EmailEncrypted).
+		Where("email_hash IS NOT DISTINCT FROM ?", old.EmailHash).
+		Where("totp_secret_encrypted IS NOT DISTINCT FROM ?", old.TOTPSecretEncrypted).
+		UpdateColumns(map[string]any{
+			"data_key":               new.DataKey,
+			"phone_number_encrypted": new.PhoneNumberEncrypted,
+			"phone_number_hash":      new.PhoneNumberHash,
+			"email_encrypted":        new.EmailEncrypted,
+			"email_hash":             new.EmailHash,
+			"totp_secret_encrypted":  new.TOTPSecretEncrypted,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// SwapRecoveryCodes replaces the recovery code hashes of the user only if they were not changed since they were read,
+// so a recovery code can not be used by concurrent logins twice
+func (ur *UserRepository) SwapRecoveryCodes(ctx context.Context, id, old, new string) (bool, error) {
+	result := ur.db.WithContext(ctx).Model(&domain.User{}).
+		Where("id = ? AND totp_recovery_codes = ?", id, old).
+		Update("totp_recovery_codes", new)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// ListAddresses retrieves the addresses of the user, none are found where the address table is not migrated.
+func (ur *UserRepository) ListAddresses(ctx context.Context, userID string) ([]domain.Address, error) {
+	var addresses []domain.Address
+	db := ur.db.WithContext(ctx)
+	if !db.Migrator().HasTable(&domain.Address{}) {
+		return addresses, nil
+	}
+	err := db.Where("user_id = ? AND deleted_at IS NULL", userID).Order("id").Find(&addresses).Error
+	return addresses, err
+}
+
+// SoftDeleteUser sets the deletion time of the user and deactivates the account.
+func (ur *UserRepository) SoftDeleteUser(ctx context.Context, id, deletedBy string, deletedAt time.Time) error {
+	result := ur.db.WithContext(ctx).Model(&domain.User{}).
+		Where("id = ? AND deleted_at IS NULL", id).
+		Updates(map[string]any{"deleted_at": deletedAt, "deleted_by": deletedBy, "is_active": false})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domain.ErrDataNotFound
+	}
+	return nil
+}
+
+// ListErasableUserIDs returns the IDs of the users deleted before the given time which are not erased, in ID order.
+func (ur *UserRepository) ListErasableUserIDs(ctx context.Context, deletedBefore time.Time, limit int) ([]string, error) {
+	var ids []string
+	err := ur.db.WithContext(ctx).Model(&domain.User{}).
+		Where("deleted_at <= ? AND erased_at IS NULL", deletedBefore).
+		Order("id").
+		Limit(limit).
+		Pluck("id", &ids).Error
+	return ids, err
+}
+
+// EraseUser wipes the personal data and the data key of the user, deletes its sessions and addresses and creates
+// the tombstone in one transaction, the role and deletion details of the tombstone are copied from the user.
+// The user row is locked with SKIP LOCKED, a user being erased by another worker or already erased returns
+// ErrDataNotFound. An interrupted erasure is rolled back and retried by the next run.
+func (ur *UserRepository) EraseUser(ctx context.Context, tombstone *domain.UserTombstone) error {
+	id := tombstone.UserID
+	return ur.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		tx, err := ur.withKeys(tx)
+		if err != nil {
+			return err
+		}
+
+		// Only the columns of the tombstone are read, erasing does not depend on decrypting the data
+		var user domain.User
+		err = tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Select("id", "role", "deleted_at", "deleted_by").
+			Where("id = ? AND deleted_at IS NOT NULL AND erased_at IS NULL", id).
+			Take(&user).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return domain.ErrDataNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		err = tx.Model(&domain.User{}).Where("id = ?", id).Updates(map[string]any{
+			"first_name":               "",
+			"last_name":                "",
+			"email_hash":               nil,
+			"email_encrypted":          nil,
+			"is_email_verified":        false,
+			"phone_number_hash":        "",
+			"phone_number_encrypted":   "",
+			"is_phone_number_verified": false,
+			"password":                 nil,
+			"totp_secret_encrypted":    nil,
+			"is_totp_enabled":          false,
+			"totp_recovery_codes":      nil,
+			"data_key":                 nil,
+			"erased_at":                tombstone.ErasedAt,
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Where("user_id = ?", id).Delete(&domain.Session{}).Error; err != nil {
+			return err
+		}
+		// Addresses are only migrated where PostGIS is available
+		if tx.Migrator().HasTable(&domain.Address{}) {
+			if err := tx.Where("user_id = ?", id).Delete(&domain.Address{}).Error; err != nil {
+				return err
+			}
+		}
+
+		tombstone.Role = user.Role
+		tombstone.RequestedAt = *user.DeletedAt
+		if user.DeletedBy != nil {
+			tombstone.DeletedBy = *user.DeletedBy
+		}
+		return tx.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "user_id"}}, DoNothing: true}).Create(tombstone).Error
+	})
+}
+
+// withKeys passes the config and key manager the AfterFind hook of users decrypts with on to the transaction.
+func (ur *UserRepository) withKeys(tx *gorm.DB) (*gorm.DB, error) {
+	c, ok := ur.db.InstanceGet("config")
+	if !ok {
+		return nil, errors.New("config not found")
+	}
+	tx = tx.InstanceSet("config", c)
+	if keys, ok := ur.db.InstanceGet("keys"); ok {
+		tx = tx.InstanceSet("keys", keys)
+	}
+	return tx, nil
+}
@@ -1,15 +1,99 @@
 package domain
 
-import "github.com/golang-jwt/jwt/v5"
+import (
+	"crypto"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// TokenType distinguishes the purpose of an issued JWT
+type TokenType string
+
+const (
+	AccessToken  TokenType = "access"
+	RefreshToken TokenType = "refresh"
+	// MFAPendingToken proves the first login factor and can only be exchanged for a token pair with a second factor
+	MFAPendingToken TokenType = "mfa_pending"
+	// APIKeyToken marks the claims of a caller authenticated with an API key, they are never signed
+	APIKeyToken TokenType = "api_key"
+	// ClientAccessToken is an access token issued to an OAuth client with the client credentials grant
+	ClientAccessToken TokenType = "client_access"
+)
 
 type JWTToken struct {
 	AccessToken  string `json:"access_token"`
 	RefreshToken string `json:"refresh_token"`
 	Role         string `json:"role"`
+	// ExpiresIn is the lifetime of the access token in seconds
+	ExpiresIn int64 `json:"expires_in"`
 }
 
 type UserClaims struct {
-	Role string `json:"role"`
-	Name string `json:"name"`
+	Role      string    `json:"role"`
+	Name      string    `json:"name"`
+	FamilyID  string    `json:"fid"`
+	TokenType TokenType `json:"typ"`
+	// MFA is set when the user logged in with a second factor
+	MFA bool `json:"mfa,omitempty"`
+	// Scopes are the permissions of an API key or OAuth client caller
+	Scopes []string `json:"scope,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// RefreshClaims are the claims carried by a refresh token, every refresh token belongs to a token family
+type RefreshClaims struct {
+	FamilyID  string    `json:"fid"`
+	TokenType TokenType `json:"typ"`
 	jwt.RegisteredClaims
 }
+
+// RefreshFamily is the server side state of a refresh token family, keyed by user and family ID
+type RefreshFamily struct {
+	// ID of the refresh token of the family which can be exchanged next.
+	TokenID string `json:"jti"`
+
+	// Set when the family was started by a login with a second factor, every token of the family carries it.
+	MFA bool `json:"mfa,omitempty"`
+}
+
+// TokenKey is a key used for signing or verifying JWTs, PrivateKey is nil for verification only keys
+type TokenKey struct {
+	ID         string
+	Algorithm  string
+	PrivateKey crypto.PrivateKey
+	PublicKey  crypto.PublicKey
+}
+
+// JWK is a public key in the JSON Web Key format (RFC 7517)
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+}
+
+// JWKS is the set of public keys tokens can be verified with
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// MFAChallenge is returned by the login endpoints instead of a token pair when the user has a second factor enabled
+type MFAChallenge struct {
+	MFARequired bool   `json:"mfa_required"`
+	MFAToken    string `json:"mfa_token"`
+}
+
+// TOTPEnrollment is the secret of a pending TOTP enrollment, URI is the otpauth:// URI authenticator apps scan
+type TOTPEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+// RecoveryCodes are the single-use codes accepted in place of a TOTP code, they are only shown once
+type RecoveryCodes struct {
+	Codes []string `json:"recovery_codes"`
+}
@@ -16,10 +16,58 @@ var (
 	ErrExpiredToken    = errors.New("access token has expired")
 	// ErrInvalidToken is an error for when the access token is invalid
 	ErrInvalidToken = errors.New("access token is invalid")
+	// ErrRevokedToken is an error for when the access token has been revoked by logging out
+	ErrRevokedToken = errors.New("access token has been revoked")
+	// ErrRefreshTokenReused is an error for when an already rotated refresh token is presented again
+	ErrRefreshTokenReused = errors.New("refresh token has already been used")
 	// ErrInvalidCredentials is an error for when the credentials are invalid
 	ErrInvalidCredentials = errors.New("invalid email or password")
+	// ErrAccountLocked is an error for when password logins are locked after too many failed attempts
+	ErrAccountLocked = errors.New("account is temporarily locked due to too many failed login attempts")
 	// ErrPasswordNotSet is an error for when password is not set for a user
 	ErrPasswordNotSet = errors.New("password not set")
+	// ErrPasswordTooShort is an error for when the password is shorter than the policy allows
+	ErrPasswordTooShort = errors.New("password is too short")
+	// ErrPasswordTooWeak is an error for when the password misses a character class required by the policy
+	ErrPasswordTooWeak = errors.New("password must contain the required character classes")
+	// ErrPasswordTooCommon is an error for when the password is a commonly used one
+	ErrPasswordTooCommon = errors.New("password is too common")
+	// ErrInvalidResetToken is an error for when the password reset token is unknown, used or expired
+	ErrInvalidResetToken = errors.New("password reset token is invalid or expired")
+	// ErrMFAAlreadyEnabled is an error for when TOTP is enrolled again after it was confirmed
+	ErrMFAAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	// ErrMFANotEnrolled is an error for when TOTP is confirmed or verified before it was enrolled
+	ErrMFANotEnrolled = errors.New("two-factor authentication is not enrolled")
+	// ErrInvalidMFACode is an error for when the TOTP or recovery code is incorrect
+	ErrInvalidMFACode = errors.New("two-factor authentication code is invalid")
+	// ErrMFAAttemptsExceeded is an error for when too many codes were tried and two-factor authentication is locked
+	ErrMFAAttemptsExceeded = errors.New("too many incorrect two-factor authentication attempts")
+	// ErrMFARequired is an error for when the resource requires a login with a second factor
+	ErrMFARequired = errors.New("two-factor authentication is required")
+	// ErrInvalidAPIKey is an error for when the API key is unknown, revoked or expired
+	ErrInvalidAPIKey = errors.New("API key is invalid")
+	// ErrInvalidScope is an error for when an API key scope is not a defined permission
+	ErrInvalidScope = errors.New("API key scope is invalid")
+	// ErrInvalidClient is an error for when the OAuth client is unknown, revoked or its secret is wrong
+	ErrInvalidClient = errors.New("client authentication failed")
+	// ErrUnauthorizedClient is an error for when the OAuth client revokes a token which was not issued to it
+	ErrUnauthorizedClient = errors.New("token was not issued to the client")
+	// ErrUnsupportedGrantType is an error for when the OAuth grant type is not supported
+	ErrUnsupportedGrantType = errors.New("grant type is not supported")
+	// ErrAccountInactive is an error for when the account was deactivated
+	ErrAccountInactive = errors.New("account is inactive")
+	// ErrInvalidMagicLink is an error for when the magic link token is unknown or was tampered with
+	ErrInvalidMagicLink = errors.New("magic link is invalid")
+	// ErrMagicLinkUsed is an error for when the magic link was already used to log in
+	ErrMagicLinkUsed = errors.New("magic link was already used")
+	// ErrMagicLinkExpired is an error for when the magic link is no longer valid
+	ErrMagicLinkExpired = errors.New("magic link has expired")
+	// ErrExportPending is an error for when a data export is requested again before the previous one was sent
+	ErrExportPending = errors.New("a data export was requested recently, try again later")
+	// ErrInvalidDownloadLink is an error for when the signature of a download link does not match
+	ErrInvalidDownloadLink = errors.New("download link is invalid")
+	// ErrDownloadLinkExpired is an error for when a download link has expired or was already used
+	ErrDownloadLinkExpired = errors.New("download link has expired or was already used")
 	// ErrEmptyAuthorizationHeader is an error for when the authorization header is empty
 	ErrEmptyAuthorizationHeader = errors.New("authorization header is not provided")
 	// ErrInvalidAuthorizationHeader is an error for when the authorization header is invalid
@@ -36,6 +84,28 @@ var (
 	ErrOTPExpired = errors.New("OTP expired")
 	// OTP mismatch
 	ErrOTPMismatch = errors.New("OTP mismatch")
+	// OTP not issued or already used
+	ErrOTPNotFound = errors.New("OTP not found or already used")
+	// OTP verify attempts exhausted
+	ErrOTPAttemptsExceeded = errors.New("too many incorrect OTP attempts")
+	// OTP requested again before the resend cooldown elapsed
+	ErrOTPResendCooldown = errors.New("OTP was sent recently, try again later")
+	// ErrUnsupportedChannel is an error for when a notification channel is not supported
+	ErrUnsupportedChannel = errors.New("notification channel is not supported")
+	// ErrNotificationFailed is an error for when a notification could not be delivered
+	ErrNotificationFailed = errors.New("notification could not be delivered")
+	// ErrUnknownTemplate is an error for when a notification template does not exist
+	ErrUnknownTemplate = errors.New("notification template does not exist")
+	// ErrInvalidTemplateData is an error for when the data does not match the template variables
+	ErrInvalidTemplateData = errors.New("invalid notification template data")
+	// ErrEmailNotVerified is an error for when an unverified email is used to log in
+	ErrEmailNotVerified = errors.New("email is not verified")
+	// ErrInvalidVerificationCode is an error for when the email or phone number verification code is incorrect, expired or not requested
+	ErrInvalidVerificationCode = errors.New("verification code is invalid or expired")
+	// ErrVerificationAttemptsExceeded is an error for when the verification code was guessed wrong too many times
+	ErrVerificationAttemptsExceeded = errors.New("too many incorrect verification attempts")
+	// ErrEmailNotSet is an error for when email is not set for a user
+	ErrEmailNotSet = errors.New("email not set")
 	//Validation errors
 	ErrValidation = errors.New("validation error")
 
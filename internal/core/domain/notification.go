@@ -0,0 +1,115 @@
+package domain
+
+import (
+	"fmt"
+	"math"
+)
+
+// NotificationChannel is a channel notifications can be delivered through
+type NotificationChannel string
+
+const (
+	ChannelSMS      NotificationChannel = "sms"
+	ChannelWhatsapp NotificationChannel = "whatsapp"
+	ChannelEmail    NotificationChannel = "email"
+	ChannelPush     NotificationChannel = "push"
+)
+
+// DefaultLocale is used when neither the requested nor the user's locale has a template variant
+const DefaultLocale = "en"
+
+// TemplateID names a notification template
+type TemplateID string
+
+const (
+	TemplateOTP           TemplateID = "otp"
+	TemplateWelcome       TemplateID = "welcome"
+	TemplatePasswordReset TemplateID = "password_reset"
+	// TemplateEmailVerification is sent to an email address before it is accepted
+	TemplateEmailVerification TemplateID = "email_verification"
+	// TemplateMagicLink carries a passwordless login link
+	TemplateMagicLink TemplateID = "magic_link"
+	// TemplateDataExport carries the download link of a personal data export
+	TemplateDataExport TemplateID = "data_export"
+)
+
+// TemplateVarType is the type of a template variable
+type TemplateVarType string
+
+const (
+	VarString TemplateVarType = "string"
+	VarInt    TemplateVarType = "int"
+)
+
+// TemplateVars declares the variables every template is rendered with
+var TemplateVars = map[TemplateID]map[string]TemplateVarType{
+	TemplateOTP: {
+		"app_name":       VarString,
+		"otp":            VarString,
+		"expiry_minutes": VarInt,
+	},
+	TemplateWelcome: {
+		"app_name":   VarString,
+		"first_name": VarString,
+	},
+	TemplatePasswordReset: {
+		"app_name":       VarString,
+		"first_name":     VarString,
+		"code":           VarString,
+		"expiry_minutes": VarInt,
+	},
+	TemplateEmailVerification: {
+		"app_name":       VarString,
+		"first_name":     VarString,
+		"code":           VarString,
+		"expiry_minutes": VarInt,
+	},
+	TemplateMagicLink: {
+		"app_name":       VarString,
+		"first_name":     VarString,
+		"link":           VarString,
+		"expiry_minutes": VarInt,
+	},
+	TemplateDataExport: {
+		"app_name":     VarString,
+		"first_name":   VarString,
+		"link":         VarString,
+		"expiry_hours": VarInt,
+	},
+}
+
+// ValidateTemplateData checks that data has every variable declared for the template with the declared type
+func ValidateTemplateData(id TemplateID, data map[string]any) error {
+	vars, ok := TemplateVars[id]
+	if !ok {
+		return ErrUnknownTemplate
+	}
+	for name, typ := range vars {
+		val, ok := data[name]
+		if !ok {
+			return fmt.Errorf("%w: %s is missing", ErrInvalidTemplateData, name)
+		}
+		switch typ {
+		case VarString:
+			_, ok = val.(string)
+		case VarInt:
+			ok = isInt(val)
+		}
+		if !ok {
+			return fmt.Errorf("%w: %s must be %s", ErrInvalidTemplateData, name, typ)
+		}
+	}
+	return nil
+}
+
+// isInt reports whether val is an integer, including integral numbers decoded from JSON as float64
+func isInt(val any) bool {
+	switch v := val.(type) {
+	case int, int64:
+		return true
+	case float64:
+		return v == math.Trunc(v)
+	default:
+		return false
+	}
+}
@@ -0,0 +1,57 @@
+package domain
+
+import (
+	"strings"
+	"time"
+)
+
+// OAuthClient is a service registered to obtain tokens with the client credentials grant, its ID is the client_id
+type OAuthClient struct {
+	BaseModel
+	Name       string     `gorm:"size:100;not null" json:"name"`
+	SecretHash string     `gorm:"size:256;not null" json:"-"`
+	Scopes     string     `gorm:"size:1024;not null" json:"scopes"`
+	CreatedBy  string     `gorm:"size:50;not null" json:"created_by"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+}
+
+// ScopeList returns the permissions the client may request, scopes are stored space separated
+func (c *OAuthClient) ScopeList() []string {
+	return strings.Fields(c.Scopes)
+}
+
+// NewOAuthClient is the result of registering an OAuth client, ClientSecret is only returned once
+type NewOAuthClient struct {
+	Client       *OAuthClient `json:"client"`
+	ClientID     string       `json:"client_id"`
+	ClientSecret string       `json:"client_secret"`
+}
+
+// OAuthToken is the successful access token response (RFC 6749 section 5.1)
+type OAuthToken struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+}
+
+// OAuthError is the error response of the OAuth endpoints (RFC 6749 section 5.2)
+type OAuthError struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+// TokenIntrospection is the introspection response (RFC 7662 section 2.2), only Active is set for inactive tokens
+type TokenIntrospection struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	Exp       int64  `json:"exp,omitempty"`
+	Iat       int64  `json:"iat,omitempty"`
+	Sub       string `json:"sub,omitempty"`
+	Iss       string `json:"iss,omitempty"`
+	Jti       string `json:"jti,omitempty"`
+	Role      string `json:"role,omitempty"`
+}
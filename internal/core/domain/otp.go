@@ -1,10 +1,66 @@
 package domain
 
-// OTP (One-Time Password) struct with Otp and OtpHash fields.
+import "time"
+
+// OTP (One-Time Password) struct with the OTP value and its validity, the OTP value is never serialized.
 type OTP struct {
 	// Actual OTP value.
-	Otp string `json:"otp"`
+	Otp string `json:"-"`
+
+	// Time after which the OTP can no longer be verified.
+	ExpiresAt time.Time `json:"expires_at"`
+
+	// Time after which a new OTP can be requested.
+	ResendAt time.Time `json:"resend_at"`
+}
+
+// OTPState is the server side state of an issued OTP, keyed by the phone number hash
+type OTPState struct {
+	// Encrypted OTP value.
+	OtpEncrypted string `json:"otp_encrypted"`
+
+	// Time after which the OTP can no longer be verified.
+	ExpiresAt time.Time `json:"expires_at"`
+
+	// Time after which a new OTP can be requested.
+	ResendAt time.Time `json:"resend_at"`
+}
+
+// EmailVerification is the server side state of a pending email change, keyed by user ID
+type EmailVerification struct {
+	// Encrypted email address to be verified.
+	EmailEncrypted string `json:"email_encrypted"`
+
+	// Hash of the verification code.
+	CodeHash string `json:"code_hash"`
+
+	// Time after which the code can no longer be verified.
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// MagicLinkState is the server side state of an issued magic link, keyed by the hash of its token
+type MagicLinkState struct {
+	// ID of the user the link logs in.
+	UserID string `json:"user_id"`
+
+	// Blind index of the email the link was sent to.
+	EmailHash string `json:"email_hash"`
+
+	// Time after which the link can no longer be used.
+	ExpiresAt time.Time `json:"expires_at"`
+
+	// Set once the link was used to log in.
+	Used bool `json:"used"`
+}
+
+// PhoneNumberVerification is the server side state of a pending phone number change, keyed by user ID
+type PhoneNumberVerification struct {
+	// Encrypted phone number to be verified.
+	PhoneNumberEncrypted string `json:"phone_number_encrypted"`
+
+	// Hash of the verification code.
+	CodeHash string `json:"code_hash"`
 
-	// Hashed OTP value.
-	OtpHash string `json:"otp_hash"`
+	// Time after which the code can no longer be verified.
+	ExpiresAt time.Time `json:"expires_at"`
 }
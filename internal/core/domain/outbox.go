@@ -0,0 +1,56 @@
+package domain
+
+import "time"
+
+// OutboxStatus is the delivery state of an outbox message
+type OutboxStatus string
+
+const (
+	OutboxPending OutboxStatus = "pending"
+	// OutboxInFlight marks messages claimed by a dispatcher, they are claimed again once the lease expired
+	OutboxInFlight OutboxStatus = "in_flight"
+	OutboxSent     OutboxStatus = "sent"
+	// OutboxDead marks messages which exhausted their delivery attempts
+	OutboxDead OutboxStatus = "dead"
+)
+
+const (
+	// OutboxTopicNotification is the topic of messages carrying a NotificationEvent
+	OutboxTopicNotification = "notification"
+	// OutboxTopicDataExport is the topic of messages carrying a DataExportEvent
+	OutboxTopicDataExport = "data_export"
+)
+
+// OutboxMessage is written in the same transaction as the change it originates from and published asynchronously.
+// While a message is in flight, NextAttemptAt is the expiry of the lease of the dispatcher holding LeaseID.
+type OutboxMessage struct {
+	BaseModel
+	Topic         string       `gorm:"size:100;not null" json:"topic"`
+	Payload       string       `gorm:"type:jsonb;not null" json:"payload"`
+	Status        OutboxStatus `gorm:"size:20;not null;index:idx_outbox_status_next_attempt" json:"status"`
+	Attempts      uint         `gorm:"not null;default:0" json:"attempts"`
+	NextAttemptAt time.Time    `gorm:"not null;index:idx_outbox_status_next_attempt" json:"next_attempt_at"`
+	LastError     string       `gorm:"size:1000" json:"last_error"`
+	LeaseID       string       `gorm:"size:26" json:"lease_id"`
+}
+
+// TableName overrides the table name of OutboxMessage
+func (OutboxMessage) TableName() string {
+	return "outbox"
+}
+
+// NotificationEvent is a notification to be delivered to a user, the recipient is resolved when publishing
+type NotificationEvent struct {
+	UserID   string              `json:"user_id"`
+	Channel  NotificationChannel `json:"channel"`
+	Template TemplateID          `json:"template"`
+	Locale   string              `json:"locale"`
+	Data     map[string]any      `json:"data"`
+}
+
+// DataExportEvent is a requested export of the personal data of a user, it is built when publishing
+type DataExportEvent struct {
+	ExportID string `json:"export_id"`
+	UserID   string `json:"user_id"`
+	Locale   string `json:"locale"`
+}
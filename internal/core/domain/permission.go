@@ -0,0 +1,53 @@
+package domain
+
+// Permission is an action a user role may be allowed to perform
+type Permission string
+
+const (
+	// PermUserReadOwn allows reading the caller's own user record
+	PermUserReadOwn Permission = "users:read:own"
+	// PermUserReadAny allows reading any user record
+	PermUserReadAny Permission = "users:read:any"
+)
+
+// permissions is the set of all defined permissions, API key and OAuth client scopes must be one of them
+var permissions = map[Permission]bool{
+	PermUserReadOwn: true,
+	PermUserReadAny: true,
+}
+
+// IsValid reports whether the permission is defined
+func (p Permission) IsValid() bool {
+	return permissions[p]
+}
+
+// rolePermissions is the permission matrix mapping every role to its allowed actions
+var rolePermissions = map[UserRole][]Permission{
+	Admin:    {PermUserReadOwn, PermUserReadAny},
+	Rider:    {PermUserReadOwn},
+	Customer: {PermUserReadOwn},
+}
+
+// HasPermission reports whether the role is allowed to perform the action
+func (r UserRole) HasPermission(p Permission) bool {
+	for _, perm := range rolePermissions[r] {
+		if perm == p {
+			return true
+		}
+	}
+	return false
+}
+
+// HasPermission reports whether the claims allow the action, API keys and OAuth clients are allowed the actions
+// of their scopes and users the actions of their role
+func (c *UserClaims) HasPermission(p Permission) bool {
+	if c.TokenType == APIKeyToken || c.TokenType == ClientAccessToken {
+		for _, scope := range c.Scopes {
+			if Permission(scope) == p {
+				return true
+			}
+		}
+		return false
+	}
+	return UserRole(c.Role).HasPermission(p)
+}
@@ -0,0 +1,20 @@
+package domain
+
+// UserCiphertexts are the encrypted and blind index columns of a user
+type UserCiphertexts struct {
+	DataKey              *string
+	PhoneNumberEncrypted string
+	PhoneNumberHash      string
+	EmailEncrypted       *string
+	EmailHash            *string
+	TOTPSecretEncrypted  *string
+}
+
+// ReencryptionProgress reports a run of the re-encryption job. A stopped run is resumed after LastID.
+type ReencryptionProgress struct {
+	Scanned     int
+	Reencrypted int
+	// Conflicts counts the users changed while they were re-encrypted, they are left for the next run
+	Conflicts int
+	LastID    string
+}
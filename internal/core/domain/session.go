@@ -0,0 +1,22 @@
+package domain
+
+import "time"
+
+// Session is a login of a user on a device, its ID is the refresh token family ID of the login
+type Session struct {
+	BaseModel
+	UserID     string    `gorm:"size:50;not null;index" json:"user_id"`
+	DeviceName string    `gorm:"size:100" json:"device_name"`
+	UserAgent  string    `gorm:"size:512" json:"user_agent"`
+	IPAddress  string    `gorm:"size:45" json:"ip_address"`
+	LastSeenAt time.Time `gorm:"not null" json:"last_seen_at"`
+	// Current is set when listing sessions for the session the request was made with
+	Current bool `gorm:"-:all" json:"current"`
+}
+
+// ClientInfo describes the client a token pair is issued to
+type ClientInfo struct {
+	DeviceName string
+	UserAgent  string
+	IPAddress  string
+}
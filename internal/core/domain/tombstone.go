@@ -0,0 +1,15 @@
+package domain
+
+import "time"
+
+// UserTombstone is the audit record left after the personal data of a deleted user was erased,
+// it holds no personal data
+type UserTombstone struct {
+	BaseModel
+	UserID string   `gorm:"size:50;not null;uniqueIndex" json:"user_id"`
+	Role   UserRole `gorm:"size:5;not null" json:"user_role"`
+	// DeletedBy is the ID of the user or admin who deleted the account
+	DeletedBy   string    `gorm:"size:50;not null" json:"deleted_by"`
+	RequestedAt time.Time `gorm:"not null" json:"requested_at"`
+	ErasedAt    time.Time `gorm:"not null" json:"erased_at"`
+}
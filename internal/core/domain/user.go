@@ -1,10 +1,12 @@
 package domain
 
 import (
+	"context"
 	"database/sql/driver"
 	"encoding/binary"
 	"errors"
 	"math"
+	"time"
 
 	"github.com/arasan1289/hexagonal-demo/internal/adapters/config"
 	"github.com/arasan1289/hexagonal-demo/internal/core/util"
@@ -20,6 +22,11 @@ const (
 	Customer UserRole = "CUS"
 )
 
+// IsValid reports whether the role is one of the defined roles
+func (r UserRole) IsValid() bool {
+	return r == Admin || r == Rider || r == Customer
+}
+
 // User represents a user in the system
 type User struct {
 	BaseModel
@@ -35,7 +42,52 @@ type User struct {
 	IsPhoneNumberVerified bool     `gorm:"default:false" json:"is_phone_number_verified"`
 	Role                  UserRole `gorm:"size:5;not null" json:"user_role"`
 	IsActive              bool     `gorm:"default:false" json:"is_active"`
-	Password              *string  `gorm:"size:256"`
+	Locale                string   `gorm:"size:35;default:en" json:"locale"`
+	TOTPSecretEncrypted   *string  `gorm:"size:256" json:"-"`
+	IsTOTPEnabled         bool     `gorm:"default:false" json:"is_totp_enabled"`
+	TOTPRecoveryCodes     *string  `gorm:"size:1024" json:"-"`
+	Password              *string  `gorm:"size:256" json:"-"`
+	// DataKey is the key the personal data of the user is encrypted with, wrapped by the key manager
+	DataKey *string `gorm:"size:256" json:"-"`
+	// DeactivatedAt is set when an admin deactivates the account and kept when it is activated again,
+	// accounts which were never deactivated are activated by their first phone number verification
+	DeactivatedAt *time.Time `json:"-"`
+	// DeletedBy is the ID of the user or admin who deleted the account
+	DeletedBy *string `gorm:"size:50" json:"-"`
+	// ErasedAt is set once the personal data of the deleted user was erased
+	ErasedAt *time.Time `gorm:"index" json:"-"`
+}
+
+// UserFilter selects the users listed by admins, nil filters match every user.
+// Users are listed in ID order starting after Cursor.
+type UserFilter struct {
+	Role                  *UserRole
+	IsActive              *bool
+	IsEmailVerified       *bool
+	IsPhoneNumberVerified *bool
+	// IdentifierHashes match the phone number hash or email hash
+	IdentifierHashes []string
+	Cursor           string
+	Limit            int
+}
+
+// UserPage is a page of listed users, NextCursor is empty on the last page
+type UserPage struct {
+	Users      []User
+	NextCursor string
+}
+
+// UserPatch holds the self-service profile changes, nil fields are left unchanged
+type UserPatch struct {
+	FirstName   *string
+	LastName    *string
+	Email       *string
+	PhoneNumber *string
+}
+
+// IsEmpty reports whether the patch changes nothing
+func (p *UserPatch) IsEmpty() bool {
+	return p.FirstName == nil && p.LastName == nil && p.Email == nil && p.PhoneNumber == nil
 }
 
 func (u *User) AfterFind(tx *gorm.DB) (err error) {
@@ -51,15 +103,30 @@ func (u *User) AfterFind(tx *gorm.DB) (err error) {
 		// Handle incorrect type
 		return errors.New("config has unexpected type")
 	}
+
+	// Fetch the data key of the user from the key manager of the GORM instance
+	var dataKey []byte
+	if u.DataKey != nil {
+		keysValue, _ := tx.InstanceGet("keys")
+		keys, ok := keysValue.(dataKeyUnwrapper)
+		if !ok {
+			return errors.New("key manager not found in GORM instance")
+		}
+		dataKey, err = keys.UnwrapDataKey(tx.Statement.Context, *u.DataKey)
+		if err != nil {
+			return err
+		}
+	}
+
 	if u.PhoneNumberEncrypted != "" {
-		p, err := util.DecryptString(u.PhoneNumberEncrypted, conf.App.SecretKey)
+		p, err := util.DecryptField(u.PhoneNumberEncrypted, dataKey, conf.App.SecretKeys()...)
 		if err != nil {
 			return err
 		}
 		u.PhoneNumber = p
 	}
 	if u.EmailEncrypted != nil {
-		e, err := util.DecryptString(*u.EmailEncrypted, conf.App.SecretKey)
+		e, err := util.DecryptField(*u.EmailEncrypted, dataKey, conf.App.SecretKeys()...)
 		if err != nil {
 			return err
 		}
@@ -68,6 +135,11 @@ func (u *User) AfterFind(tx *gorm.DB) (err error) {
 	return nil
 }
 
+// dataKeyUnwrapper is the part of the key manager AfterFind decrypts with
+type dataKeyUnwrapper interface {
+	UnwrapDataKey(ctx context.Context, wrapped string) ([]byte, error)
+}
+
 type Address struct {
 	BaseModel
 	UserID      string `gorm:"not null" json:"user_id"`
@@ -0,0 +1,32 @@
+package port
+
+import (
+	"context"
+	"time"
+
+	"github.com/arasan1289/hexagonal-demo/internal/core/domain"
+)
+
+// IAPIKeyRepository interface defines the methods for interacting with the API key data
+type IAPIKeyRepository interface {
+	// CreateAPIKey inserts the API key
+	CreateAPIKey(ctx context.Context, key *domain.APIKey) error
+	// GetAPIKeyByHash retrieves the API key by the hash of the key
+	GetAPIKeyByHash(ctx context.Context, hash string) (*domain.APIKey, error)
+	// ListAPIKeys returns all API keys, newest first
+	ListAPIKeys(ctx context.Context) ([]domain.APIKey, error)
+	// UpdateAPIKey updates only the given columns of the API key
+	UpdateAPIKey(ctx context.Context, key *domain.APIKey, columns ...string) error
+}
+
+// IAPIKeyService interface defines the methods for managing and verifying API keys
+type IAPIKeyService interface {
+	// CreateAPIKey creates an API key with the scopes, the plain key is only returned here
+	CreateAPIKey(ctx context.Context, name string, scopes []string, expiresAt *time.Time, createdBy string) (*domain.NewAPIKey, error)
+	// ListAPIKeys returns all API keys without their secrets
+	ListAPIKeys(ctx context.Context) ([]domain.APIKey, error)
+	// RevokeAPIKey revokes the API key, it can no longer be used
+	RevokeAPIKey(ctx context.Context, id string) error
+	// VerifyAPIKey checks the key and returns the claims of its caller
+	VerifyAPIKey(ctx context.Context, key string) (*domain.UserClaims, error)
+}
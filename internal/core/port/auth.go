@@ -8,7 +8,30 @@ import (
 
 // IAuthService defines the interface for authentication and authorizations related operations
 type IAuthService interface {
-	GenerateJWT(ctx context.Context, user *domain.User) (*domain.JWTToken, error)
+	// GenerateJWT issues a new token pair and records it as a session of the client, mfa is only set after a second factor
+	GenerateJWT(ctx context.Context, user *domain.User, client *domain.ClientInfo, mfa bool) (*domain.JWTToken, error)
+	// VerifyJWT verifies an access token, tokens of revoked sessions and inactive users are rejected
 	VerifyJWT(ctx context.Context, accessToken string) (*domain.UserClaims, error)
-	RefreshJWT(ctx context.Context, refreshToken string, user *domain.User) (*domain.JWTToken, error)
+	// RefreshJWT rotates the refresh token and issues a new token pair
+	RefreshJWT(ctx context.Context, refreshToken string) (*domain.JWTToken, error)
+	// JWKS returns the public keys tokens can be verified with
+	JWKS(ctx context.Context) *domain.JWKS
+	// RevokeJWT revokes the access token and the refresh token family it was issued with
+	RevokeJWT(ctx context.Context, claims *domain.UserClaims) error
+	// RevokeAllJWT revokes every token issued to the user so far
+	RevokeAllJWT(ctx context.Context, userID string) error
+	// ListSessions returns the active sessions of the user, flagging the one with currentSessionID
+	ListSessions(ctx context.Context, userID, currentSessionID string) ([]domain.Session, error)
+	// RevokeSession revokes the tokens of one session of the user
+	RevokeSession(ctx context.Context, userID, sessionID string) error
+	// GenerateClientJWT issues an access token with the scopes to the OAuth client
+	GenerateClientJWT(ctx context.Context, client *domain.OAuthClient, scopes []string) (*domain.JWTToken, error)
+	// IntrospectJWT reports whether the token is active and returns its claims
+	IntrospectJWT(ctx context.Context, token string) (*domain.TokenIntrospection, error)
+	// RevokeToken revokes an access token on behalf of the OAuth client it was issued to
+	RevokeToken(ctx context.Context, clientID, token string) error
+	// GenerateMFAToken issues a short-lived token to be exchanged for a token pair with a second factor
+	GenerateMFAToken(ctx context.Context, user *domain.User) (string, error)
+	// VerifyMFAToken verifies the token was issued by GenerateMFAToken and is not used yet
+	VerifyMFAToken(ctx context.Context, mfaToken string) (*domain.UserClaims, error)
 }
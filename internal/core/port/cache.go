@@ -11,6 +11,12 @@ type ICache interface {
 	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
 	// Get retrieves the value from the cache
 	Get(ctx context.Context, key string) ([]byte, error)
+	// CompareAndSwap atomically replaces the value with new if it still equals old, and reports whether it did
+	CompareAndSwap(ctx context.Context, key string, old, new []byte, ttl time.Duration) (bool, error)
+	// GetDelete atomically retrieves and removes the value from the cache, so only one caller gets it
+	GetDelete(ctx context.Context, key string) ([]byte, error)
+	// Increment atomically increments the counter and returns its new value, the TTL is set by the first increment
+	Increment(ctx context.Context, key string, ttl time.Duration) (int64, error)
 	// Delete removes the value from the cache
 	Delete(ctx context.Context, key string) error
 	// DeleteByPrefix removes the value from the cache with the given prefix
@@ -0,0 +1,11 @@
+package port
+
+import (
+	"context"
+)
+
+// IErasureService interface defines the methods of the job erasing the personal data of deleted users
+type IErasureService interface {
+	// Run erases the users whose deletion grace period has passed until the context is cancelled
+	Run(ctx context.Context)
+}
@@ -0,0 +1,20 @@
+package port
+
+import (
+	"context"
+
+	"github.com/arasan1289/hexagonal-demo/internal/core/domain"
+)
+
+// IDataExportService defines the interface for exporting the personal data of users
+type IDataExportService interface {
+	// RequestExport queues an export of the user's data, the user is notified with a download link once it is built
+	RequestExport(ctx context.Context, userID, locale string) error
+
+	// BuildExport collects the user's data into a ZIP bundle and notifies the user
+	BuildExport(ctx context.Context, event *domain.DataExportEvent) error
+
+	// DownloadExport checks the signed download link and returns the bundle decrypted with the key of the link,
+	// every bundle is downloaded once
+	DownloadExport(ctx context.Context, id, expires, key, signature string) ([]byte, error)
+}
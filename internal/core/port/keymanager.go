@@ -0,0 +1,21 @@
+package port
+
+import (
+	"context"
+)
+
+// IKeyManager wraps the data keys personal data is encrypted with under a master key it does not reveal
+type IKeyManager interface {
+	// GenerateDataKey returns a new data key and the data key wrapped by the current master key,
+	// the wrapped key is prefixed with the version of the master key
+	GenerateDataKey(ctx context.Context) (dataKey []byte, wrapped string, err error)
+
+	// UnwrapDataKey decrypts a wrapped data key with the master key version of its prefix
+	UnwrapDataKey(ctx context.Context, wrapped string) ([]byte, error)
+
+	// WrapDataKey wraps an existing data key with the current master key
+	WrapDataKey(ctx context.Context, dataKey []byte) (string, error)
+
+	// CurrentKeyPrefix returns the prefix of data keys wrapped by the current master key
+	CurrentKeyPrefix() string
+}
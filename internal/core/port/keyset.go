@@ -0,0 +1,15 @@
+package port
+
+import "github.com/arasan1289/hexagonal-demo/internal/core/domain"
+
+// ITokenKeySet provides the keys used for signing and verifying JWTs
+type ITokenKeySet interface {
+	// SigningKey returns the key new tokens are signed with, nil when no asymmetric key is configured
+	SigningKey() *domain.TokenKey
+
+	// VerificationKey returns the key with the given key ID
+	VerificationKey(kid string) (*domain.TokenKey, error)
+
+	// JWKS returns the public keys of all active keys
+	JWKS() *domain.JWKS
+}
@@ -0,0 +1,16 @@
+package port
+
+import (
+	"context"
+
+	"github.com/arasan1289/hexagonal-demo/internal/core/domain"
+)
+
+// IMagicLinkService defines the interface for passwordless login by email links
+type IMagicLinkService interface {
+	// SendMagicLink emails a single-use login link to the user with the verified email
+	SendMagicLink(ctx context.Context, email, locale string) error
+
+	// VerifyMagicLink consumes the link token and returns the user it was sent to
+	VerifyMagicLink(ctx context.Context, token string) (*domain.User, error)
+}
@@ -0,0 +1,17 @@
+package port
+
+import (
+	"context"
+
+	"github.com/arasan1289/hexagonal-demo/internal/core/domain"
+)
+
+// IMFAService defines the interface for two-factor authentication related operations
+type IMFAService interface {
+	// EnrollTOTP generates a new TOTP secret for the user, it is only enabled once confirmed
+	EnrollTOTP(ctx context.Context, userID string) (*domain.TOTPEnrollment, error)
+	// ConfirmTOTP enables TOTP once the user proves the secret was enrolled and returns the recovery codes
+	ConfirmTOTP(ctx context.Context, userID, code string) (*domain.RecoveryCodes, error)
+	// VerifyLogin exchanges the MFA token and a TOTP or recovery code for a token pair of the client
+	VerifyLogin(ctx context.Context, mfaToken, code string, client *domain.ClientInfo) (*domain.JWTToken, error)
+}
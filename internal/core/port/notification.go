@@ -1,16 +1,23 @@
 package port
 
+import (
+	"context"
+
+	"github.com/arasan1289/hexagonal-demo/internal/core/domain"
+)
+
 // INotificationService interface defines methods for sending notifications (SMS, Email, Push, Whatsapp).
+// Messages are rendered from the template in the given locale with the data.
 type INotificationService interface {
 	// SendSMS sends an SMS to the specified recipient using the given template.
-	SendSMS(to string, template string) (bool, error)
+	SendSMS(ctx context.Context, to string, template domain.TemplateID, locale string, data map[string]any) (bool, error)
 
 	// SendEmail sends an email to the specified recipient using the given template.
-	SendEmail(to string, template string) (bool, error)
+	SendEmail(ctx context.Context, to string, template domain.TemplateID, locale string, data map[string]any) (bool, error)
 
 	// SendPush sends a push notification to the specified recipient using the given template.
-	SendPush(to string, template string) (bool, error)
+	SendPush(ctx context.Context, to string, template domain.TemplateID, locale string, data map[string]any) (bool, error)
 
 	// SendWhatsapp sends a Whatsapp message to the specified recipient using the given template.
-	SendWhatsapp(to string, template string) (bool, error)
+	SendWhatsapp(ctx context.Context, to string, template domain.TemplateID, locale string, data map[string]any) (bool, error)
 }
@@ -0,0 +1,30 @@
+package port
+
+import (
+	"context"
+
+	"github.com/arasan1289/hexagonal-demo/internal/core/domain"
+)
+
+// IOAuthClientRepository interface defines the methods for interacting with the OAuth client data
+type IOAuthClientRepository interface {
+	// CreateClient inserts the OAuth client
+	CreateClient(ctx context.Context, client *domain.OAuthClient) error
+	// GetClient retrieves the OAuth client by ID
+	GetClient(ctx context.Context, id string) (*domain.OAuthClient, error)
+	// UpdateClient updates only the given columns of the OAuth client
+	UpdateClient(ctx context.Context, client *domain.OAuthClient, columns ...string) error
+}
+
+// IOAuthService interface defines the methods of the OAuth authorization server
+type IOAuthService interface {
+	// RegisterClient registers an OAuth client allowed the scopes, the plain secret is only returned here
+	RegisterClient(ctx context.Context, name string, scopes []string, createdBy string) (*domain.NewOAuthClient, error)
+	// RevokeClient revokes the OAuth client, it can no longer obtain tokens and its issued tokens stop working
+	RevokeClient(ctx context.Context, id string) error
+	// AuthenticateClient verifies the client credentials
+	AuthenticateClient(ctx context.Context, clientID, clientSecret string) (*domain.OAuthClient, error)
+	// ClientCredentials issues an access token to the authenticated client for the requested space separated scopes,
+	// all of the client's scopes are granted when scope is empty
+	ClientCredentials(ctx context.Context, client *domain.OAuthClient, scope string) (*domain.OAuthToken, error)
+}
@@ -8,9 +8,13 @@ import (
 
 // OtpService defines the interface for OTP related operations
 type IOtpService interface {
-	// GenerateOTP generates a new OTP with the given length
-	GenerateOTP(ctx context.Context, length uint) (*domain.OTP, error)
+	// GenerateOTP generates a new OTP for the phone number and stores its state
+	GenerateOTP(ctx context.Context, phoneNumber string) (*domain.OTP, error)
 
-	// VerifyOTP verifies if the given OTP is valid
-	VerifyOTP(ctx context.Context, otp *domain.OTP) (bool, error)
+	// SendOTP generates a new OTP for the user's phone number and delivers it through the channel,
+	// the user's preferred locale is used when locale is empty
+	SendOTP(ctx context.Context, user *domain.User, channel domain.NotificationChannel, locale string) (*domain.OTP, error)
+
+	// VerifyOTP verifies if the given OTP is valid for the phone number and consumes it
+	VerifyOTP(ctx context.Context, phoneNumber, otp string) (bool, error)
 }
@@ -0,0 +1,28 @@
+package port
+
+import (
+	"context"
+	"time"
+
+	"github.com/arasan1289/hexagonal-demo/internal/core/domain"
+)
+
+// IOutboxRepository interface defines the methods for interacting with the outbox
+type IOutboxRepository interface {
+	// ClaimPending marks up to limit due messages, pending or with an expired lease, in flight under the
+	// lease until leasedUntil and counts an attempt for each of them, skipping messages being claimed by other dispatchers
+	ClaimPending(ctx context.Context, leaseID string, leasedUntil time.Time, limit int) ([]domain.OutboxMessage, error)
+
+	// CompleteMessage saves the outcome of publishing the claimed message, ErrDataNotFound is returned
+	// when its lease expired and another dispatcher claimed it
+	CompleteMessage(ctx context.Context, msg *domain.OutboxMessage) error
+
+	// CreateMessages writes outbox messages which do not belong to another change
+	CreateMessages(ctx context.Context, msgs ...*domain.OutboxMessage) error
+}
+
+// IOutboxService interface defines the methods of the outbox dispatcher
+type IOutboxService interface {
+	// Run publishes the pending outbox messages until the context is cancelled
+	Run(ctx context.Context)
+}
@@ -0,0 +1,19 @@
+package port
+
+import (
+	"context"
+
+	"github.com/arasan1289/hexagonal-demo/internal/core/domain"
+)
+
+// IPasswordService defines the interface for password management operations
+type IPasswordService interface {
+	// ForgotPassword sends a single-use password reset token to the user identified by phone number or email
+	ForgotPassword(ctx context.Context, identifier string, channel domain.NotificationChannel, locale string) error
+
+	// ChangePassword sets or changes the user's password, the current password is required when one is set
+	ChangePassword(ctx context.Context, userID, currentPassword, newPassword string) error
+
+	// ResetPassword sets a new password using the reset token and revokes all of the user's tokens
+	ResetPassword(ctx context.Context, token, password string) error
+}
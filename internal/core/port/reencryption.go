@@ -0,0 +1,14 @@
+package port
+
+import (
+	"context"
+
+	"github.com/arasan1289/hexagonal-demo/internal/core/domain"
+)
+
+// IReencryptionService interface defines the methods of the job moving personal data to the current keys
+type IReencryptionService interface {
+	// Reencrypt walks the users after the given ID in batches and re-encrypts the users still on old keys or
+	// blind indexes, the progress is returned when the context is cancelled or an error occurs
+	Reencrypt(ctx context.Context, after string, batchSize int) (*domain.ReencryptionProgress, error)
+}
@@ -0,0 +1,22 @@
+package port
+
+import (
+	"context"
+	"time"
+
+	"github.com/arasan1289/hexagonal-demo/internal/core/domain"
+)
+
+// ISessionRepository interface defines the methods for interacting with the session data
+type ISessionRepository interface {
+	// CreateSession records a new session
+	CreateSession(ctx context.Context, session *domain.Session) error
+	// TouchSession updates the last seen time of the session
+	TouchSession(ctx context.Context, id string, lastSeenAt time.Time) error
+	// ListSessions returns the sessions of the user, most recently seen first
+	ListSessions(ctx context.Context, userID string) ([]domain.Session, error)
+	// DeleteSession deletes the session of the user, returns ErrDataNotFound when the user has no such session
+	DeleteSession(ctx context.Context, userID, id string) error
+	// DeleteUserSessions deletes every session of the user
+	DeleteUserSessions(ctx context.Context, userID string) error
+}
@@ -2,6 +2,7 @@ package port
 
 import (
 	"context"
+	"time"
 
 	"github.com/arasan1289/hexagonal-demo/internal/adapters/config"
 	"github.com/arasan1289/hexagonal-demo/internal/core/domain"
@@ -9,14 +10,52 @@ import (
 
 // IUserRepository interface defines the methods for interacting with the user repository
 type IUserRepository interface {
-	// UpsertUser inserts or updates a user in the repository
-	UpsertUser(ctx context.Context, user *domain.User) (*domain.User, error)
+	// UpsertUser inserts or updates a user in the repository together with the outbox messages
+	UpsertUser(ctx context.Context, user *domain.User, outbox ...*domain.OutboxMessage) (*domain.User, error)
+
+	// UpdateUser updates only the given columns of the user
+	UpdateUser(ctx context.Context, user *domain.User, columns ...string) (*domain.User, error)
 
 	// GetUser retrieves a user from the repository by ID
 	GetUser(ctx context.Context, id string) (*domain.User, error)
 
-	// GetUserByPhoneNumber retrieves a user from the repository by phone number hash
-	GetUserByPhoneNumberOrEmail(ctx context.Context, hash string) (*domain.User, error)
+	// GetUserByPhoneNumber retrieves a user from the repository by any of the given phone number or email hashes,
+	// the owner of a verified email is preferred over users who entered it without verifying it
+	GetUserByPhoneNumberOrEmail(ctx context.Context, hashes ...string) (*domain.User, error)
+
+	// ClearUnverifiedEmails removes the email with any of the given hashes from the users other than the given user
+	// who have not verified it
+	ClearUnverifiedEmails(ctx context.Context, userID string, hashes ...string) error
+
+	// ListUsers retrieves up to filter.Limit users matching the filter with an ID after filter.Cursor, in ID order
+	ListUsers(ctx context.Context, filter *domain.UserFilter) ([]domain.User, error)
+
+	// ListUsersAfter retrieves up to limit users with an ID after the given ID in ID order, including soft deleted users
+	ListUsersAfter(ctx context.Context, after string, limit int) ([]domain.User, error)
+
+	// SetDataKey stores the wrapped data key of the user unless the user already has one and returns the stored key
+	SetDataKey(ctx context.Context, id, wrapped string) (string, error)
+
+	// SwapUserCiphertexts replaces the encrypted columns of the user when they still hold the old ciphertexts,
+	// returns false when the user was changed in the meantime
+	SwapUserCiphertexts(ctx context.Context, id string, old, new *domain.UserCiphertexts) (bool, error)
+
+	// SwapRecoveryCodes replaces the recovery code hashes of the user when they still hold the old hashes,
+	// returns false when they were changed in the meantime
+	SwapRecoveryCodes(ctx context.Context, id, old, new string) (bool, error)
+
+	// ListAddresses retrieves the addresses of the user
+	ListAddresses(ctx context.Context, userID string) ([]domain.Address, error)
+
+	// SoftDeleteUser marks the user deleted and inactive, returns ErrDataNotFound when the user is already deleted
+	SoftDeleteUser(ctx context.Context, id, deletedBy string, deletedAt time.Time) error
+
+	// ListErasableUserIDs returns up to limit IDs of users deleted before the given time whose data is not erased yet
+	ListErasableUserIDs(ctx context.Context, deletedBefore time.Time, limit int) ([]string, error)
+
+	// EraseUser erases the personal data of the deleted user tombstone.UserID and records the tombstone,
+	// returns ErrDataNotFound when the user is already erased or being erased
+	EraseUser(ctx context.Context, tombstone *domain.UserTombstone) error
 }
 
 // UserService interface defines the methods for interacting with the user service
@@ -32,4 +71,38 @@ type IUserService interface {
 
 	// GetUserAndComparePassword retrieves a user from repository by phone number or email and compares the password
 	GetUserAndComparePassword(ctx context.Context, email, password string) (*domain.User, bool, error)
+
+	// ListUsers lists a page of the users matching the filter, search is an exact email or phone number
+	ListUsers(ctx context.Context, filter *domain.UserFilter, search string) (*domain.UserPage, error)
+
+	// SetRole changes the role of the user
+	SetRole(ctx context.Context, id string, role domain.UserRole) (*domain.User, error)
+
+	// SetActive activates or deactivates the account of the user
+	SetActive(ctx context.Context, id string, active bool) (*domain.User, error)
+
+	// DeleteUser deletes the account and logs the user out, the personal data is erased after the grace period
+	DeleteUser(ctx context.Context, id, deletedBy string) error
+
+	// UnlockUser lifts the lockout caused by failed logins
+	UnlockUser(ctx context.Context, id string) error
+
+	// ChangeEmail sends a verification code to the new email, it replaces the user's email once verified
+	ChangeEmail(ctx context.Context, userID, email, locale string) error
+
+	// ChangePhoneNumber sends an OTP to the new phone number, it replaces the user's phone number once verified
+	ChangePhoneNumber(ctx context.Context, userID, phoneNumber, locale string) error
+
+	// UpdateProfile applies the self-service profile changes of the user, email and phone number changes are sent
+	// for verification
+	UpdateProfile(ctx context.Context, userID string, patch *domain.UserPatch) (*domain.User, error)
+
+	// ConfirmPhoneNumber marks the phone number of the user verified and activates the account unless it was deactivated
+	ConfirmPhoneNumber(ctx context.Context, user *domain.User) (*domain.User, error)
+
+	// VerifyEmail verifies the code of the pending email change and marks the email verified
+	VerifyEmail(ctx context.Context, userID, code string) (*domain.User, error)
+
+	// VerifyPhoneNumber verifies the OTP of the pending phone number change and marks the phone number verified
+	VerifyPhoneNumber(ctx context.Context, userID, code string) (*domain.User, error)
 }
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/arasan1289/hexagonal-demo/internal/adapters/logger"
+	"github.com/arasan1289/hexagonal-demo/internal/core/domain"
+	"github.com/arasan1289/hexagonal-demo/internal/core/port"
+	"github.com/arasan1289/hexagonal-demo/internal/core/util"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// apiKeyPrefix marks API keys so they are recognisable in logs and secret scanners
+	apiKeyPrefix = "hxk_"
+	// apiKeyLastUsedInterval limits how often the last used time of a key is written
+	apiKeyLastUsedInterval = time.Minute
+)
+
+// APIKeyService struct represents the API key service with its dependencies
+type APIKeyService struct {
+	repo port.IAPIKeyRepository // API key repository interface
+	log  *logger.Logger         // logger instance
+}
+
+// NewAPIKeyService constructor function
+func NewAPIKeyService(repo port.IAPIKeyRepository, log *logger.Logger) port.IAPIKeyService {
+	return &APIKeyService{
+		repo: repo,
+		log:  log,
+	}
+}
+
+// CreateAPIKey generates a random key and stores its hash, every scope must be a defined permission
+func (ks *APIKeyService) CreateAPIKey(ctx context.Context, name string, scopes []string, expiresAt *time.Time, createdBy string) (*domain.NewAPIKey, error) {
+	for _, scope := range scopes {
+		if !domain.Permission(scope).IsValid() {
+			return nil, domain.ErrInvalidScope
+		}
+	}
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return nil, domain.ErrValidation
+	}
+
+	token, err := util.GenerateToken(32)
+	if err != nil {
+		return nil, err
+	}
+	key := apiKeyPrefix + token
+
+	apiKey := &domain.APIKey{
+		BaseModel: domain.BaseModel{ID: util.GenerateULID()},
+		Name:      name,
+		Prefix:    key[:len(apiKeyPrefix)+6],
+		KeyHash:   util.HashString(key),
+		Scopes:    joinScopes(scopes),
+		CreatedBy: createdBy,
+		ExpiresAt: expiresAt,
+	}
+	if err := ks.repo.CreateAPIKey(ctx, apiKey); err != nil {
+		return nil, err
+	}
+	ks.log.Info().Str("api_key_id", apiKey.ID).Str("created_by", createdBy).Msg("API key created")
+
+	return &domain.NewAPIKey{APIKey: apiKey, Key: key}, nil
+}
+
+// ListAPIKeys returns all API keys
+func (ks *APIKeyService) ListAPIKeys(ctx context.Context) ([]domain.APIKey, error) {
+	return ks.repo.ListAPIKeys(ctx)
+}
+
+// RevokeAPIKey marks the API key revoked
+func (ks *APIKeyService) RevokeAPIKey(ctx context.Context, id string) error {
+	now := time.Now()
+	key := &domain.APIKey{BaseModel: domain.BaseModel{ID: id}, RevokedAt: &now}
+	return ks.repo.UpdateAPIKey(ctx, key, "revoked_at")
+}
+
+// VerifyAPIKey looks the key up by its hash and returns claims carrying its scopes, the last used time is
+// recorded at most once per apiKeyLastUsedInterval
+func (ks *APIKeyService) VerifyAPIKey(ctx context.Context, key string) (*domain.UserClaims, error) {
+	apiKey, err := ks.repo.GetAPIKeyByHash(ctx, util.HashString(key))
+	if err != nil {
+		if errors.Is(err, domain.ErrDataNotFound) {
+			return nil, domain.ErrInvalidAPIKey
+		}
+		return nil, err
+	}
+
+	now := time.Now()
+	if !apiKey.IsUsable(now) {
+		return nil, domain.ErrInvalidAPIKey
+	}
+
+	if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) > apiKeyLastUsedInterval {
+		apiKey.LastUsedAt = &now
+		if err := ks.repo.UpdateAPIKey(ctx, apiKey, "last_used_at"); err != nil {
+			// A failed bookkeeping write does not deny the request
+			ks.log.Error().Err(err).Str("api_key_id", apiKey.ID).Msg("Error updating API key last used time")
+		}
+	}
+
+	claims := &domain.UserClaims{
+		Name:      apiKey.Name,
+		TokenType: domain.APIKeyToken,
+		Scopes:    apiKey.ScopeList(),
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:      apiKey.ID,
+			Subject: apiKey.ID,
+		},
+	}
+	if apiKey.ExpiresAt != nil {
+		claims.ExpiresAt = jwt.NewNumericDate(*apiKey.ExpiresAt)
+	}
+	return claims, nil
+}
+
+// joinScopes stores the scopes space separated without duplicates
+func joinScopes(scopes []string) string {
+	seen := make(map[string]bool, len(scopes))
+	var unique []string
+	for _, scope := range scopes {
+		if !seen[scope] {
+			seen[scope] = true
+			unique = append(unique, scope)
+		}
+	}
+	return strings.Join(unique, " ")
+}
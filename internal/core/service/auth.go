@@ -2,97 +2,565 @@ package service
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/arasan1289/hexagonal-demo/internal/adapters/config"
 	"github.com/arasan1289/hexagonal-demo/internal/adapters/logger"
 	"github.com/arasan1289/hexagonal-demo/internal/core/domain"
 	"github.com/arasan1289/hexagonal-demo/internal/core/port"
+	"github.com/arasan1289/hexagonal-demo/internal/core/util"
 	"github.com/golang-jwt/jwt/v5"
 )
 
+const (
+	accessTokenTTL  = time.Hour * 24
+	refreshTokenTTL = time.Hour * 24 * 30
+	mfaTokenTTL     = time.Minute * 5
+	clientTokenTTL  = time.Hour
+
+	// refreshFamilyPrefix prefixes the cache keys holding the state of every refresh token family
+	refreshFamilyPrefix = "refresh_family:"
+	// revokedTokenPrefix prefixes the cache keys of revoked access token IDs
+	revokedTokenPrefix = "revoked_token:"
+	// revokedBeforePrefix prefixes the cache keys holding the time before which all tokens of a user are revoked
+	revokedBeforePrefix = "revoked_before:"
+	// userActivePrefix prefixes the cache keys remembering for userActiveTTL that a user was found active
+	userActivePrefix = "user_active:"
+	userActiveTTL    = time.Second * 30
+)
+
 type AuthService struct {
-	log    *logger.Logger
-	config *config.App
+	log         *logger.Logger
+	config      *config.App
+	keys        port.ITokenKeySet
+	cache       port.ICache
+	userRepo    port.IUserRepository
+	sessionRepo port.ISessionRepository
 }
 
 // NewOtpService constructor function
-func NewAuthService(log *logger.Logger, config *config.App) port.IAuthService {
+func NewAuthService(log *logger.Logger, config *config.App, keys port.ITokenKeySet, cache port.ICache, userRepo port.IUserRepository, sessionRepo port.ISessionRepository) port.IAuthService {
 	return &AuthService{
-		log:    log,
-		config: config,
+		log:         log,
+		config:      config,
+		keys:        keys,
+		cache:       cache,
+		userRepo:    userRepo,
+		sessionRepo: sessionRepo,
+	}
+}
+
+// GenerateJWT issues an access and refresh token pair starting a new refresh token family,
+// the family is recorded as a session of the client. mfa marks the family as logged in with a second factor.
+func (as *AuthService) GenerateJWT(ctx context.Context, user *domain.User, client *domain.ClientInfo, mfa bool) (*domain.JWTToken, error) {
+	if !user.IsActive {
+		return nil, domain.ErrAccountInactive
+	}
+	familyID := util.GenerateULID()
+	now := time.Now()
+	session := &domain.Session{
+		BaseModel:  domain.BaseModel{ID: familyID},
+		UserID:     user.ID,
+		LastSeenAt: now,
+	}
+	if client != nil {
+		session.DeviceName = client.DeviceName
+		session.UserAgent = client.UserAgent
+		session.IPAddress = client.IPAddress
+	}
+	if err := as.sessionRepo.CreateSession(ctx, session); err != nil {
+		return nil, err
+	}
+	return as.issueTokens(ctx, user, familyID, mfa, nil)
+}
+
+func (as *AuthService) VerifyJWT(ctx context.Context, accessToken string) (*domain.UserClaims, error) {
+	claims := &domain.UserClaims{}
+	if err := as.parseToken(accessToken, claims); err != nil {
+		return nil, err
+	}
+	if claims.TokenType != domain.AccessToken && claims.TokenType != domain.ClientAccessToken {
+		return nil, domain.ErrInvalidToken
+	}
+
+	revoked, err := as.isRevoked(ctx, claims)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, domain.ErrRevokedToken
+	}
+	if claims.TokenType == domain.ClientAccessToken {
+		// Client tokens do not belong to a session
+		return claims, nil
+	}
+
+	// The session of the token was revoked once its refresh token family is gone
+	if _, err := as.cache.Get(ctx, refreshFamilyKey(claims.Subject, claims.FamilyID)); err != nil {
+		if errors.Is(err, domain.ErrDataNotFound) {
+			return nil, domain.ErrRevokedToken
+		}
+		return nil, err
+	}
+
+	// A deactivated or deleted user is locked out even if revoking the tokens failed
+	if err := as.checkUserActive(ctx, claims.Subject); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// checkUserActive returns ErrAccountInactive for deactivated users and ErrRevokedToken for deleted ones.
+// Active users are remembered for userActiveTTL, so authenticated requests do not read the user every time.
+func (as *AuthService) checkUserActive(ctx context.Context, userID string) error {
+	key := userActivePrefix + userID
+	_, err := as.cache.Get(ctx, key)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, domain.ErrDataNotFound) {
+		return err
+	}
+
+	user, err := as.userRepo.GetUser(ctx, userID)
+	if err != nil {
+		if errors.Is(err, domain.ErrDataNotFound) {
+			return domain.ErrRevokedToken
+		}
+		return err
+	}
+	if !user.IsActive {
+		return domain.ErrAccountInactive
+	}
+	return as.cache.Set(ctx, key, []byte{1}, userActiveTTL)
+}
+
+// RefreshJWT exchanges a refresh token for a new token pair of the same family and invalidates the used one.
+// Presenting a refresh token which was already exchanged, also by a concurrent request, revokes the whole family.
+func (as *AuthService) RefreshJWT(ctx context.Context, refreshToken string) (*domain.JWTToken, error) {
+	claims := &domain.RefreshClaims{}
+	if err := as.parseToken(refreshToken, claims); err != nil {
+		return nil, err
+	}
+	if claims.TokenType != domain.RefreshToken || claims.FamilyID == "" || claims.ID == "" {
+		return nil, domain.ErrInvalidToken
 	}
+
+	family, val, err := as.getRefreshFamily(ctx, claims.Subject, claims.FamilyID)
+	if err != nil {
+		if errors.Is(err, domain.ErrDataNotFound) {
+			// The family has expired or was revoked
+			return nil, domain.ErrInvalidToken
+		}
+		return nil, err
+	}
+
+	if family.TokenID != claims.ID {
+		return nil, as.revokeReusedFamily(ctx, claims)
+	}
+
+	user, err := as.userRepo.GetUser(ctx, claims.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if !user.IsActive {
+		return nil, domain.ErrAccountInactive
+	}
+
+	if err := as.sessionRepo.TouchSession(ctx, claims.FamilyID, time.Now()); err != nil {
+		return nil, err
+	}
+	// The second factor of the login the family was started with is carried forward
+	tokens, err := as.issueTokens(ctx, user, claims.FamilyID, family.MFA, val)
+	if errors.Is(err, domain.ErrRefreshTokenReused) {
+		// Another request exchanged the same token in the meantime
+		return nil, as.revokeReusedFamily(ctx, claims)
+	}
+	return tokens, err
+}
+
+// revokeReusedFamily drops the family of a replayed refresh token, it may have been stolen so nobody keeps the family
+func (as *AuthService) revokeReusedFamily(ctx context.Context, claims *domain.RefreshClaims) error {
+	if err := as.cache.Delete(ctx, refreshFamilyKey(claims.Subject, claims.FamilyID)); err != nil {
+		return err
+	}
+	if err := as.sessionRepo.DeleteSession(ctx, claims.Subject, claims.FamilyID); err != nil && !errors.Is(err, domain.ErrDataNotFound) {
+		return err
+	}
+	as.log.Warn().
+		Str("user_id", claims.Subject).
+		Str("family_id", claims.FamilyID).
+		Msg("Refresh token reuse detected, token family revoked")
+	return domain.ErrRefreshTokenReused
+}
+
+// JWKS returns the public keys other services can verify tokens with
+func (as *AuthService) JWKS(ctx context.Context) *domain.JWKS {
+	return as.keys.JWKS()
+}
+
+// RevokeJWT denylists the access token until it expires and drops its refresh token family
+func (as *AuthService) RevokeJWT(ctx context.Context, claims *domain.UserClaims) error {
+	if claims.ID != "" && claims.ExpiresAt != nil {
+		ttl := time.Until(claims.ExpiresAt.Time)
+		if ttl > 0 {
+			if err := as.cache.Set(ctx, revokedTokenPrefix+claims.ID, []byte{1}, ttl); err != nil {
+				return err
+			}
+		}
+	}
+	if claims.FamilyID != "" {
+		return as.RevokeSession(ctx, claims.Subject, claims.FamilyID)
+	}
+	return nil
+}
+
+// RevokeSession drops the refresh token family of the session, which also invalidates its access tokens
+func (as *AuthService) RevokeSession(ctx context.Context, userID, sessionID string) error {
+	if err := as.sessionRepo.DeleteSession(ctx, userID, sessionID); err != nil && !errors.Is(err, domain.ErrDataNotFound) {
+		return err
+	}
+	return as.cache.Delete(ctx, refreshFamilyKey(userID, sessionID))
+}
+
+// ListSessions returns the active sessions of the user, currentSessionID is flagged as the current one
+func (as *AuthService) ListSessions(ctx context.Context, userID, currentSessionID string) ([]domain.Session, error) {
+	sessions, err := as.sessionRepo.ListSessions(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	active := make([]domain.Session, 0, len(sessions))
+	for _, s := range sessions {
+		// A session whose refresh token has not been used within its lifetime has expired
+		if time.Since(s.LastSeenAt) > refreshTokenTTL {
+			continue
+		}
+		s.Current = s.ID == currentSessionID
+		active = append(active, s)
+	}
+	return active, nil
+}
+
+// RevokeAllJWT revokes every access token of the user issued until now and drops all of the user's refresh token families.
+// The remembered active state is dropped as well, so a deactivated or deleted user is read again.
+func (as *AuthService) RevokeAllJWT(ctx context.Context, userID string) error {
+	// Access tokens issued before this point expire within accessTokenTTL, the marker is not needed after that
+	revokedBefore := strconv.FormatInt(time.Now().Unix(), 10)
+	if err := as.cache.Set(ctx, revokedBeforePrefix+userID, []byte(revokedBefore), accessTokenTTL); err != nil {
+		return err
+	}
+	if err := as.cache.Delete(ctx, userActivePrefix+userID); err != nil {
+		return err
+	}
+	if err := as.sessionRepo.DeleteUserSessions(ctx, userID); err != nil {
+		return err
+	}
+	return as.cache.DeleteByPrefix(ctx, refreshFamilyPrefix+userID+":")
 }
 
-func (as *AuthService) GenerateJWT(ctx context.Context, user *domain.User) (*domain.JWTToken, error) {
-	key := []byte(as.config.JWTSecret)
+// GenerateMFAToken issues a short-lived token proving the user passed the first login factor
+func (as *AuthService) GenerateMFAToken(ctx context.Context, user *domain.User) (string, error) {
+	if !user.IsActive {
+		return "", domain.ErrAccountInactive
+	}
+	now := time.Now()
+	claims := domain.UserClaims{
+		Role:      string(user.Role),
+		TokenType: domain.MFAPendingToken,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        util.GenerateULID(),
+			ExpiresAt: jwt.NewNumericDate(now.Add(mfaTokenTTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			Issuer:    "auth-server",
+			Subject:   user.ID,
+		},
+	}
+	return as.sign(claims)
+}
+
+// VerifyMFAToken verifies the MFA token, it is single-use once revoked with RevokeJWT
+func (as *AuthService) VerifyMFAToken(ctx context.Context, mfaToken string) (*domain.UserClaims, error) {
+	claims := &domain.UserClaims{}
+	if err := as.parseToken(mfaToken, claims); err != nil {
+		return nil, err
+	}
+	if claims.TokenType != domain.MFAPendingToken || claims.ID == "" {
+		return nil, domain.ErrInvalidToken
+	}
+
+	revoked, err := as.isRevoked(ctx, claims)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, domain.ErrRevokedToken
+	}
+	return claims, nil
+}
+
+// GenerateClientJWT issues an access token to the OAuth client carrying the granted scopes, no refresh token is issued
+func (as *AuthService) GenerateClientJWT(ctx context.Context, client *domain.OAuthClient, scopes []string) (*domain.JWTToken, error) {
+	now := time.Now()
+	claims := domain.UserClaims{
+		Name:      client.Name,
+		TokenType: domain.ClientAccessToken,
+		Scopes:    scopes,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        util.GenerateULID(),
+			ExpiresAt: jwt.NewNumericDate(now.Add(clientTokenTTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			Issuer:    "auth-server",
+			Subject:   client.ID,
+		},
+	}
+	accessToken, err := as.sign(claims)
+	if err != nil {
+		return nil, err
+	}
+	return &domain.JWTToken{
+		AccessToken: accessToken,
+		ExpiresIn:   int64(clientTokenTTL.Seconds()),
+	}, nil
+}
+
+// IntrospectJWT reports whether the access or refresh token is active and its claims (RFC 7662).
+// Invalid, expired and revoked tokens are reported inactive rather than as errors.
+func (as *AuthService) IntrospectJWT(ctx context.Context, token string) (*domain.TokenIntrospection, error) {
+	claims := &domain.UserClaims{}
+	if err := as.parseToken(token, claims); err != nil {
+		return &domain.TokenIntrospection{Active: false}, nil
+	}
+
+	var active bool
+	var err error
+	switch claims.TokenType {
+	case domain.AccessToken, domain.ClientAccessToken:
+		_, err = as.VerifyJWT(ctx, token)
+		active = err == nil
+	case domain.RefreshToken:
+		var family *domain.RefreshFamily
+		family, _, err = as.getRefreshFamily(ctx, claims.Subject, claims.FamilyID)
+		active = err == nil && family.TokenID == claims.ID
+		if active {
+			// Deactivated users can not exchange their refresh tokens
+			err = as.checkUserActive(ctx, claims.Subject)
+			active = err == nil
+		}
+	}
+	if err != nil && !isTokenError(err) && !errors.Is(err, domain.ErrDataNotFound) {
+		return nil, err
+	}
+	if !active {
+		return &domain.TokenIntrospection{Active: false}, nil
+	}
+
+	rsp := &domain.TokenIntrospection{
+		Active: true,
+		Scope:  strings.Join(claims.Scopes, " "),
+		Sub:    claims.Subject,
+		Iss:    claims.Issuer,
+		Jti:    claims.ID,
+		Role:   claims.Role,
+	}
+	if claims.TokenType == domain.ClientAccessToken {
+		rsp.ClientID = claims.Subject
+	}
+	if claims.TokenType != domain.RefreshToken {
+		rsp.TokenType = "Bearer"
+	}
+	if claims.ExpiresAt != nil {
+		rsp.Exp = claims.ExpiresAt.Unix()
+	}
+	if claims.IssuedAt != nil {
+		rsp.Iat = claims.IssuedAt.Unix()
+	}
+	return rsp, nil
+}
+
+// RevokeToken revokes a token on behalf of the OAuth client it was issued to (RFC 7009), unknown tokens are ignored.
+// Only client access tokens are issued to OAuth clients, tokens of other clients and of users are refused.
+func (as *AuthService) RevokeToken(ctx context.Context, clientID, token string) error {
+	claims := &domain.UserClaims{}
+	if err := as.parseToken(token, claims); err != nil {
+		return nil
+	}
+
+	if claims.TokenType != domain.ClientAccessToken || claims.Subject != clientID {
+		return domain.ErrUnauthorizedClient
+	}
+	return as.RevokeJWT(ctx, claims)
+}
+
+// isTokenError reports whether the error means the token is not valid, as opposed to a failure verifying it.
+// Tokens of deactivated users are not valid either.
+func isTokenError(err error) bool {
+	return errors.Is(err, domain.ErrInvalidToken) || errors.Is(err, domain.ErrExpiredToken) ||
+		errors.Is(err, domain.ErrRevokedToken) || errors.Is(err, domain.ErrAccountInactive)
+}
+
+// isRevoked checks the access token against the token denylist and the user's revocation timestamp
+func (as *AuthService) isRevoked(ctx context.Context, claims *domain.UserClaims) (bool, error) {
+	if claims.ID != "" {
+		_, err := as.cache.Get(ctx, revokedTokenPrefix+claims.ID)
+		if err == nil {
+			return true, nil
+		}
+		if !errors.Is(err, domain.ErrDataNotFound) {
+			return false, err
+		}
+	}
+
+	val, err := as.cache.Get(ctx, revokedBeforePrefix+claims.Subject)
+	if err != nil {
+		if errors.Is(err, domain.ErrDataNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	revokedBefore, err := strconv.ParseInt(string(val), 10, 64)
+	if err != nil {
+		return false, err
+	}
+	// Tokens issued within the same second as the revocation are treated as revoked
+	return claims.IssuedAt == nil || claims.IssuedAt.Unix() <= revokedBefore, nil
+}
+
+// issueTokens signs a new token pair and records the refresh token as the current one of its family.
+// When rotating, the family state replaces previous only if that is still the stored one, otherwise
+// ErrRefreshTokenReused is returned.
+func (as *AuthService) issueTokens(ctx context.Context, user *domain.User, familyID string, mfa bool, previous []byte) (*domain.JWTToken, error) {
+	now := time.Now()
 	atClaims := domain.UserClaims{
-		Role: string(user.Role),
-		Name: user.FirstName + " " + user.LastName,
+		Role:      string(user.Role),
+		Name:      user.FirstName + " " + user.LastName,
+		FamilyID:  familyID,
+		TokenType: domain.AccessToken,
+		MFA:       mfa,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        util.GenerateULID(),
+			ExpiresAt: jwt.NewNumericDate(now.Add(accessTokenTTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			Issuer:    "auth-server",
+			Subject:   user.ID,
+		},
+	}
+	rtClaims := domain.RefreshClaims{
+		FamilyID:  familyID,
+		TokenType: domain.RefreshToken,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour * 24)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ID:        util.GenerateULID(),
+			ExpiresAt: jwt.NewNumericDate(now.Add(refreshTokenTTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
 			Issuer:    "auth-server",
 			Subject:   user.ID,
 		},
 	}
-	at := jwt.NewWithClaims(jwt.SigningMethodHS512, atClaims)
-	rt := jwt.NewWithClaims(jwt.SigningMethodHS512, jwt.MapClaims{
-		"exp": time.Now().Add(time.Hour * 24 * 30).Unix(),
-		"iss": "auth-server",
-		"sub": user.ID,
-	})
+	accessToken, err := as.sign(atClaims)
+	if err != nil {
+		return nil, err
+	}
 
-	accessToken, err := at.SignedString(key)
+	refreshToken, err := as.sign(rtClaims)
 	if err != nil {
 		return nil, err
 	}
 
-	refreshToken, err := rt.SignedString(key)
+	// Only the latest refresh token of a family can be exchanged, the swap makes sure a token is exchanged once
+	family, err := json.Marshal(domain.RefreshFamily{TokenID: rtClaims.ID, MFA: mfa})
 	if err != nil {
 		return nil, err
 	}
+	familyKey := refreshFamilyKey(user.ID, familyID)
+	if previous == nil {
+		if err := as.cache.Set(ctx, familyKey, family, refreshTokenTTL); err != nil {
+			return nil, err
+		}
+	} else {
+		swapped, err := as.cache.CompareAndSwap(ctx, familyKey, previous, family, refreshTokenTTL)
+		if err != nil {
+			return nil, err
+		}
+		if !swapped {
+			return nil, domain.ErrRefreshTokenReused
+		}
+	}
 
 	return &domain.JWTToken{
 		AccessToken:  accessToken,
 		RefreshToken: refreshToken,
 		Role:         string(user.Role),
+		ExpiresIn:    int64(accessTokenTTL.Seconds()),
 	}, nil
 }
 
-func (as *AuthService) VerifyJWT(ctx context.Context, accessToken string) (*domain.UserClaims, error) {
-	t, err := jwt.ParseWithClaims(accessToken, &domain.UserClaims{}, func(t *jwt.Token) (interface{}, error) {
-		return []byte(as.config.JWTSecret), nil
-	}, jwt.WithLeeway(5*time.Second))
+// sign signs the claims with the configured signing key and sets its key ID in the header.
+// Without an asymmetric signing key, tokens are signed with the shared JWT secret.
+func (as *AuthService) sign(claims jwt.Claims) (string, error) {
+	key := as.keys.SigningKey()
+	if key == nil {
+		return jwt.NewWithClaims(jwt.SigningMethodHS512, claims).SignedString([]byte(as.config.JWTSecret))
+	}
+	t := jwt.NewWithClaims(jwt.GetSigningMethod(key.Algorithm), claims)
+	t.Header["kid"] = key.ID
+	return t.SignedString(key.PrivateKey)
+}
 
-	claims, ok := t.Claims.(*domain.UserClaims)
+// keyFunc resolves the verification key by the kid header of the token.
+// Tokens without kid are only accepted while the shared JWT secret is configured.
+func (as *AuthService) keyFunc(t *jwt.Token) (interface{}, error) {
+	kid, _ := t.Header["kid"].(string)
+	if kid == "" {
+		if as.config.JWTSecret == "" || t.Method != jwt.SigningMethodHS512 {
+			return nil, domain.ErrInvalidToken
+		}
+		return []byte(as.config.JWTSecret), nil
+	}
 
-	switch {
-	case t.Valid:
-		return claims, nil
-	case errors.Is(err, jwt.ErrTokenMalformed), errors.Is(err, jwt.ErrTokenSignatureInvalid), !ok:
-		return nil, domain.ErrInvalidToken
-	case errors.Is(err, jwt.ErrTokenExpired) || errors.Is(err, jwt.ErrTokenNotValidYet):
-		return nil, domain.ErrExpiredToken
-	default:
+	key, err := as.keys.VerificationKey(kid)
+	if err != nil {
+		return nil, err
+	}
+	if t.Method.Alg() != key.Algorithm {
 		return nil, domain.ErrInvalidToken
 	}
+	return key.PublicKey, nil
 }
 
-func (as *AuthService) RefreshJWT(ctx context.Context, refreshToken string, user *domain.User) (*domain.JWTToken, error) {
-	t, err := jwt.Parse(refreshToken, func(t *jwt.Token) (interface{}, error) {
-		return []byte(as.config.JWTSecret), nil
-	}, jwt.WithLeeway(5*time.Second))
+// parseToken verifies the signature and validity of the token and decodes it into claims
+func (as *AuthService) parseToken(tokenStr string, claims jwt.Claims) error {
+	validMethods := []string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg(), jwt.SigningMethodHS512.Alg()}
+	t, err := jwt.ParseWithClaims(tokenStr, claims, as.keyFunc, jwt.WithLeeway(5*time.Second), jwt.WithValidMethods(validMethods))
 
 	switch {
-	case t.Valid:
-		return as.GenerateJWT(ctx, user)
-	case errors.Is(err, jwt.ErrTokenMalformed), errors.Is(err, jwt.ErrTokenSignatureInvalid):
-		return nil, domain.ErrInvalidToken
+	case err == nil && t.Valid:
+		return nil
 	case errors.Is(err, jwt.ErrTokenExpired) || errors.Is(err, jwt.ErrTokenNotValidYet):
-		return nil, domain.ErrExpiredToken
+		return domain.ErrExpiredToken
 	default:
-		return nil, domain.ErrInvalidToken
+		return domain.ErrInvalidToken
+	}
+}
+
+// getRefreshFamily returns the state of the refresh token family and its stored value, the value is needed to swap it.
+// Families stored before the state was kept hold the plain token ID.
+func (as *AuthService) getRefreshFamily(ctx context.Context, userID, familyID string) (*domain.RefreshFamily, []byte, error) {
+	val, err := as.cache.Get(ctx, refreshFamilyKey(userID, familyID))
+	if err != nil {
+		return nil, nil, err
+	}
+	family := &domain.RefreshFamily{}
+	if err := json.Unmarshal(val, family); err != nil {
+		family.TokenID = string(val)
 	}
+	return family, val, nil
+}
+
+// refreshFamilyKey builds the cache key of a refresh token family, scoped by user so all families of a user share a prefix
+func refreshFamilyKey(userID, familyID string) string {
+	return fmt.Sprintf("%s%s:%s", refreshFamilyPrefix, userID, familyID)
 }
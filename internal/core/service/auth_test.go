@@ -0,0 +1,225 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/arasan1289/hexagonal-demo/internal/adapters/config"
+	"github.com/arasan1289/hexagonal-demo/internal/core/domain"
+)
+
+// newTestAuthService returns an auth service with one active user "user-1"
+func newTestAuthService() (*AuthService, *fakeCache, *fakeUserRepository) {
+	cache := newFakeCache()
+	users := &fakeUserRepository{users: map[string]*domain.User{
+		"user-1": {BaseModel: domain.BaseModel{ID: "user-1"}, FirstName: "Test", LastName: "User", Role: domain.Customer, IsActive: true},
+	}}
+	as := &AuthService{
+		log:         testLogger,
+		config:      &config.App{JWTSecret: "test-jwt-secret"},
+		keys:        fakeTokenKeySet{},
+		cache:       cache,
+		userRepo:    users,
+		sessionRepo: newFakeSessionRepository(),
+	}
+	return as, cache, users
+}
+
+func TestRefreshJWT(t *testing.T) {
+	ctx := context.Background()
+	as, _, users := newTestAuthService()
+	user, _ := users.GetUser(ctx, "user-1")
+
+	first, err := as.GenerateJWT(ctx, user, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := as.RefreshJWT(ctx, first.RefreshToken)
+	if err != nil {
+		t.Fatalf("RefreshJWT() error = %v", err)
+	}
+	if second.RefreshToken == first.RefreshToken {
+		t.Fatal("RefreshJWT() did not rotate the refresh token")
+	}
+	if _, err := as.VerifyJWT(ctx, second.AccessToken); err != nil {
+		t.Fatalf("VerifyJWT() of the refreshed access token error = %v", err)
+	}
+
+	// Replaying the exchanged token revokes the whole family
+	if _, err := as.RefreshJWT(ctx, first.RefreshToken); !errors.Is(err, domain.ErrRefreshTokenReused) {
+		t.Fatalf("RefreshJWT() of a used token error = %v, want %v", err, domain.ErrRefreshTokenReused)
+	}
+	if _, err := as.RefreshJWT(ctx, second.RefreshToken); !errors.Is(err, domain.ErrInvalidToken) {
+		t.Errorf("RefreshJWT() after reuse error = %v, want %v", err, domain.ErrInvalidToken)
+	}
+	if _, err := as.VerifyJWT(ctx, second.AccessToken); !errors.Is(err, domain.ErrRevokedToken) {
+		t.Errorf("VerifyJWT() after reuse error = %v, want %v", err, domain.ErrRevokedToken)
+	}
+}
+
+func TestRefreshJWTConcurrent(t *testing.T) {
+	ctx := context.Background()
+	as, _, users := newTestAuthService()
+	user, _ := users.GetUser(ctx, "user-1")
+	tokens, err := as.GenerateJWT(ctx, user, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const requests = 20
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var succeeded, reused int
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := as.RefreshJWT(ctx, tokens.RefreshToken)
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				succeeded++
+			case errors.Is(err, domain.ErrRefreshTokenReused), errors.Is(err, domain.ErrInvalidToken):
+				reused++
+			default:
+				t.Errorf("RefreshJWT() unexpected error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if succeeded != 1 {
+		t.Errorf("%d concurrent exchanges of the same refresh token succeeded, want 1", succeeded)
+	}
+	if succeeded+reused != requests {
+		t.Errorf("got %d successes and %d rejections, want %d requests", succeeded, reused, requests)
+	}
+}
+
+func TestIntrospectJWT(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name   string
+		token  func(t *testing.T, as *AuthService, users *fakeUserRepository) string
+		active bool
+	}{
+		{
+			name: "access token",
+			token: func(t *testing.T, as *AuthService, users *fakeUserRepository) string {
+				return newTestTokens(t, as, users).AccessToken
+			},
+			active: true,
+		},
+		{
+			name: "refresh token",
+			token: func(t *testing.T, as *AuthService, users *fakeUserRepository) string {
+				return newTestTokens(t, as, users).RefreshToken
+			},
+			active: true,
+		},
+		{
+			name: "exchanged refresh token",
+			token: func(t *testing.T, as *AuthService, users *fakeUserRepository) string {
+				tokens := newTestTokens(t, as, users)
+				if _, err := as.RefreshJWT(ctx, tokens.RefreshToken); err != nil {
+					t.Fatal(err)
+				}
+				return tokens.RefreshToken
+			},
+		},
+		{
+			name: "revoked access token",
+			token: func(t *testing.T, as *AuthService, users *fakeUserRepository) string {
+				tokens := newTestTokens(t, as, users)
+				if err := as.RevokeAllJWT(ctx, "user-1"); err != nil {
+					t.Fatal(err)
+				}
+				return tokens.AccessToken
+			},
+		},
+		{
+			name: "access token of a deactivated user",
+			token: func(t *testing.T, as *AuthService, users *fakeUserRepository) string {
+				tokens := newTestTokens(t, as, users)
+				// Deactivated without revoking the tokens
+				users.users["user-1"].IsActive = false
+				return tokens.AccessToken
+			},
+		},
+		{
+			name: "refresh token of a deactivated user",
+			token: func(t *testing.T, as *AuthService, users *fakeUserRepository) string {
+				tokens := newTestTokens(t, as, users)
+				users.users["user-1"].IsActive = false
+				return tokens.RefreshToken
+			},
+		},
+		{
+			name: "client access token",
+			token: func(t *testing.T, as *AuthService, users *fakeUserRepository) string {
+				client := &domain.OAuthClient{BaseModel: domain.BaseModel{ID: "client-1"}, Name: "Client"}
+				tokens, err := as.GenerateClientJWT(ctx, client, []string{"users:read"})
+				if err != nil {
+					t.Fatal(err)
+				}
+				return tokens.AccessToken
+			},
+			active: true,
+		},
+		{
+			name: "malformed token",
+			token: func(t *testing.T, as *AuthService, users *fakeUserRepository) string {
+				return "not-a-token"
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			as, _, users := newTestAuthService()
+			rsp, err := as.IntrospectJWT(ctx, tt.token(t, as, users))
+			if err != nil {
+				t.Fatalf("IntrospectJWT() error = %v", err)
+			}
+			if rsp.Active != tt.active {
+				t.Errorf("IntrospectJWT() active = %v, want %v", rsp.Active, tt.active)
+			}
+		})
+	}
+}
+
+func TestVerifyJWTDeactivatedUser(t *testing.T) {
+	ctx := context.Background()
+	as, _, users := newTestAuthService()
+	tokens := newTestTokens(t, as, users)
+	if _, err := as.VerifyJWT(ctx, tokens.AccessToken); err != nil {
+		t.Fatal(err)
+	}
+
+	// Deactivating revokes the tokens and drops the remembered active state
+	users.users["user-1"].IsActive = false
+	if err := as.RevokeAllJWT(ctx, "user-1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := as.VerifyJWT(ctx, tokens.AccessToken); err == nil {
+		t.Error("VerifyJWT() accepted the token of a deactivated user")
+	}
+	if err := as.checkUserActive(ctx, "user-1"); !errors.Is(err, domain.ErrAccountInactive) {
+		t.Errorf("checkUserActive() error = %v, want %v", err, domain.ErrAccountInactive)
+	}
+}
+
+// newTestTokens returns a token pair of "user-1"
+func newTestTokens(t *testing.T, as *AuthService, users *fakeUserRepository) *domain.JWTToken {
+	user, err := users.GetUser(context.Background(), "user-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokens, err := as.GenerateJWT(context.Background(), user, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tokens
+}
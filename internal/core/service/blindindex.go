@@ -0,0 +1,50 @@
+package service
+
+import (
+	"crypto/subtle"
+
+	"github.com/arasan1289/hexagonal-demo/internal/adapters/config"
+	"github.com/arasan1289/hexagonal-demo/internal/core/util"
+)
+
+const defaultBlindIndexVersion = 1
+
+// blindIndex computes the keyed hash new phone numbers and emails are stored and looked up with
+func blindIndex(conf *config.App, str string) string {
+	return util.BlindIndex(str, conf.BlindIndexKey, blindIndexVersion(conf.BlindIndexVersion))
+}
+
+// blindIndexes returns every hash a phone number or email may be stored with. Until the reencrypt command
+// rewrote them, users hold the hash of the old blind index key or, when created before the hashes were keyed,
+// the unkeyed SHA-256 hash, so those are matched as well.
+func blindIndexes(conf *config.App, str string) []string {
+	hashes := []string{blindIndex(conf, str)}
+	if conf.OldBlindIndexKey != "" {
+		hashes = append(hashes, util.BlindIndex(str, conf.OldBlindIndexKey, blindIndexVersion(conf.OldBlindIndexVersion)))
+	}
+	if !conf.BlindIndexBackfilled {
+		hashes = append(hashes, util.HashString(str))
+	}
+	return hashes
+}
+
+// matchesBlindIndex reports whether the stored hash is one of the hashes of the phone number or email
+func matchesBlindIndex(conf *config.App, stored *string, str string) bool {
+	if stored == nil {
+		return false
+	}
+	for _, hash := range blindIndexes(conf, str) {
+		if subtle.ConstantTimeCompare([]byte(*stored), []byte(hash)) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// blindIndexVersion falls back to the default version tag
+func blindIndexVersion(version uint) uint {
+	if version == 0 {
+		return defaultBlindIndexVersion
+	}
+	return version
+}
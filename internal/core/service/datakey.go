@@ -0,0 +1,50 @@
+package service
+
+import (
+	"context"
+
+	"github.com/arasan1289/hexagonal-demo/internal/core/domain"
+	"github.com/arasan1289/hexagonal-demo/internal/core/port"
+	"github.com/arasan1289/hexagonal-demo/internal/core/util"
+)
+
+// userDataKey returns the data key of the user, a data key is generated for users without one. The key of a saved
+// user is only stored when the user has none yet and then read back, so concurrent requests all encrypt with the
+// key that was stored first. New users are saved with their generated key.
+func userDataKey(ctx context.Context, repo port.IUserRepository, keys port.IKeyManager, user *domain.User) ([]byte, error) {
+	if user.DataKey == nil {
+		_, wrapped, err := keys.GenerateDataKey(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if user.ID != "" {
+			if wrapped, err = repo.SetDataKey(ctx, user.ID, wrapped); err != nil {
+				return nil, err
+			}
+		}
+		user.DataKey = &wrapped
+	}
+	return keys.UnwrapDataKey(ctx, *user.DataKey)
+}
+
+// encryptUserData encrypts personal data of the user with the user's data key
+func encryptUserData(ctx context.Context, repo port.IUserRepository, keys port.IKeyManager, user *domain.User, str string) (string, error) {
+	dataKey, err := userDataKey(ctx, repo, keys, user)
+	if err != nil {
+		return "", err
+	}
+	return util.EncryptWithDataKey(str, dataKey)
+}
+
+// decryptUserData decrypts personal data of the user, data encrypted before the user had a data key is
+// decrypted with the secret keys
+func decryptUserData(ctx context.Context, keys port.IKeyManager, user *domain.User, encStr string, secrets []string) (string, error) {
+	var dataKey []byte
+	if user.DataKey != nil && util.IsDataKeyEncrypted(encStr) {
+		var err error
+		if dataKey, err = keys.UnwrapDataKey(ctx, *user.DataKey); err != nil {
+			return "", err
+		}
+	}
+	return util.DecryptField(encStr, dataKey, secrets...)
+}
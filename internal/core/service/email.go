@@ -0,0 +1,175 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/arasan1289/hexagonal-demo/internal/core/domain"
+	"github.com/arasan1289/hexagonal-demo/internal/core/util"
+)
+
+const (
+	emailVerificationTTL         = time.Minute * 30
+	emailVerificationCodeLength  = 6
+	emailVerificationMaxAttempts = 5
+
+	// emailVerificationPrefix prefixes the cache keys of pending email changes, keyed by user ID
+	emailVerificationPrefix = "email_verification:"
+	// emailAttemptsPrefix prefixes the cache keys of the verify attempt counters, keyed by user ID
+	emailAttemptsPrefix = "email_attempts:"
+)
+
+// ChangeEmail sends a verification code to the new email address, the email of the user is only
+// replaced once the code is verified. Requesting again replaces the pending change.
+func (us *UserService) ChangeEmail(ctx context.Context, userID, email, locale string) error {
+	user, err := us.repo.GetUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := us.checkEmailAvailable(ctx, userID, email); err != nil {
+		return err
+	}
+
+	code, err := util.GenerateNumericCode(emailVerificationCodeLength)
+	if err != nil {
+		return err
+	}
+	emailEnc, err := util.EncryptString(email, us.config.SecretKey)
+	if err != nil {
+		return err
+	}
+	state := domain.EmailVerification{
+		EmailEncrypted: emailEnc,
+		CodeHash:       util.HashString(code),
+		ExpiresAt:      time.Now().Add(emailVerificationTTL),
+	}
+	val, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	key := emailVerificationPrefix + userID
+	if err := us.cache.Set(ctx, key, val, emailVerificationTTL); err != nil {
+		return err
+	}
+	// The new code gets a fresh attempt budget
+	if err := us.cache.Delete(ctx, emailAttemptsPrefix+userID); err != nil {
+		return err
+	}
+
+	if locale == "" {
+		locale = user.Locale
+	}
+	data := map[string]any{
+		"app_name":       us.config.Name,
+		"first_name":     user.FirstName,
+		"code":           code,
+		"expiry_minutes": int(emailVerificationTTL.Minutes()),
+	}
+	if err := sendNotification(ctx, us.notifier, domain.ChannelEmail, email, domain.TemplateEmailVerification, locale, data); err != nil {
+		if delErr := us.cache.Delete(ctx, key); delErr != nil {
+			us.log.Error().Err(delErr).Msg("Error discarding undelivered email verification")
+		}
+		return err
+	}
+	return nil
+}
+
+// VerifyEmail checks the code of the pending email change, then stores the email as the user's verified email.
+// Attempts are counted atomically and the pending change is consumed atomically, so parallel guesses can neither
+// exceed the attempt limit nor apply the change twice.
+func (us *UserService) VerifyEmail(ctx context.Context, userID, code string) (*domain.User, error) {
+	key := emailVerificationPrefix + userID
+	val, err := us.cache.Get(ctx, key)
+	if err != nil {
+		if errors.Is(err, domain.ErrDataNotFound) {
+			return nil, domain.ErrInvalidVerificationCode
+		}
+		return nil, err
+	}
+	var state domain.EmailVerification
+	if err := json.Unmarshal(val, &state); err != nil {
+		return nil, err
+	}
+	if !time.Now().Before(state.ExpiresAt) {
+		return nil, domain.ErrInvalidVerificationCode
+	}
+	attempts, err := us.cache.Increment(ctx, emailAttemptsPrefix+userID, time.Until(state.ExpiresAt))
+	if err != nil {
+		return nil, err
+	}
+	if attempts > emailVerificationMaxAttempts {
+		return nil, domain.ErrVerificationAttemptsExceeded
+	}
+	if subtle.ConstantTimeCompare([]byte(state.CodeHash), []byte(util.HashString(code))) != 1 {
+		if attempts >= emailVerificationMaxAttempts {
+			return nil, domain.ErrVerificationAttemptsExceeded
+		}
+		return nil, domain.ErrInvalidVerificationCode
+	}
+
+	consumed, err := us.cache.GetDelete(ctx, key)
+	if err != nil {
+		if errors.Is(err, domain.ErrDataNotFound) {
+			return nil, domain.ErrInvalidVerificationCode
+		}
+		return nil, err
+	}
+	if string(consumed) != string(val) {
+		// Another change was requested in the meantime, it was consumed by mistake and has to be requested again
+		return nil, domain.ErrInvalidVerificationCode
+	}
+	if err := us.cache.Delete(ctx, emailAttemptsPrefix+userID); err != nil {
+		us.log.Error().Err(err).Msg("Error deleting email verification attempts")
+	}
+
+	email, err := util.DecryptField(state.EmailEncrypted, nil, us.config.SecretKeys()...)
+	if err != nil {
+		return nil, err
+	}
+	// The address may have been taken while the change was pending
+	if err := us.checkEmailAvailable(ctx, userID, email); err != nil {
+		return nil, err
+	}
+
+	user, err := us.repo.GetUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	emailEnc, err := encryptUserData(ctx, us.repo, us.keys, user, email)
+	if err != nil {
+		return nil, err
+	}
+	emailHash := blindIndex(us.config, email)
+	user.Email = &email
+	user.EmailEncrypted = &emailEnc
+	user.EmailHash = &emailHash
+	user.IsEmailVerified = true
+	user, err = us.repo.UpdateUser(ctx, user, "email_encrypted", "email_hash", "is_email_verified")
+	if err != nil {
+		return nil, err
+	}
+	// Whoever else entered the address without owning it loses it
+	if err := us.repo.ClearUnverifiedEmails(ctx, userID, blindIndexes(us.config, email)...); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// checkEmailAvailable returns ErrConflictingData when another user has verified the email. Unverified emails do
+// not block the address, otherwise anyone could keep its owner from using it by entering it first.
+func (us *UserService) checkEmailAvailable(ctx context.Context, userID, email string) error {
+	other, err := us.repo.GetUserByPhoneNumberOrEmail(ctx, blindIndexes(us.config, email)...)
+	if err != nil {
+		if errors.Is(err, domain.ErrDataNotFound) {
+			return nil
+		}
+		return err
+	}
+	if other.ID != userID && other.IsEmailVerified {
+		return domain.ErrConflictingData
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/arasan1289/hexagonal-demo/internal/adapters/config"
+	"github.com/arasan1289/hexagonal-demo/internal/adapters/logger"
+	"github.com/arasan1289/hexagonal-demo/internal/core/domain"
+	"github.com/arasan1289/hexagonal-demo/internal/core/port"
+	"github.com/arasan1289/hexagonal-demo/internal/core/util"
+)
+
+const (
+	defaultDeletionGracePeriod = 30 // days
+	erasurePollInterval        = time.Minute * 10
+	erasureBatchSize           = 50
+)
+
+// ErasureService erases the personal data of deleted users after the deletion grace period
+type ErasureService struct {
+	repo   port.IUserRepository
+	log    *logger.Logger
+	config *config.App
+}
+
+// NewErasureService constructor function
+func NewErasureService(repo port.IUserRepository, log *logger.Logger, config *config.App) port.IErasureService {
+	return &ErasureService{
+		repo:   repo,
+		log:    log,
+		config: config,
+	}
+}
+
+// Run erases the due users every poll interval until the context is cancelled. Every user is erased in
+// its own transaction, so a stopped run continues with the users left over in the next run.
+func (es *ErasureService) Run(ctx context.Context) {
+	ticker := time.NewTicker(erasurePollInterval)
+	defer ticker.Stop()
+
+	for {
+		// Keep erasing while full batches are returned
+		for {
+			n, err := es.erase(ctx)
+			if err != nil {
+				es.log.Error().Err(err).Msg("Error erasing deleted users")
+				break
+			}
+			if n < erasureBatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// erase erases one batch of users whose grace period has passed, users erased concurrently by another
+// instance are skipped
+func (es *ErasureService) erase(ctx context.Context) (int, error) {
+	ids, err := es.repo.ListErasableUserIDs(ctx, time.Now().Add(-es.gracePeriod()), erasureBatchSize)
+	if err != nil {
+		return 0, err
+	}
+	var erased int
+	for _, id := range ids {
+		tombstone := &domain.UserTombstone{
+			BaseModel: domain.BaseModel{ID: util.GenerateULID()},
+			UserID:    id,
+			ErasedAt:  time.Now(),
+		}
+		err := es.repo.EraseUser(ctx, tombstone)
+		if errors.Is(err, domain.ErrDataNotFound) {
+			continue
+		}
+		if err != nil {
+			return erased, err
+		}
+		erased++
+		es.log.Info().Str("user_id", id).Msg("Erased personal data of deleted user")
+	}
+	return erased, nil
+}
+
+func (es *ErasureService) gracePeriod() time.Duration {
+	days := es.config.DeletionGracePeriod
+	if days == 0 {
+		days = defaultDeletionGracePeriod
+	}
+	return time.Duration(days) * time.Hour * 24
+}
@@ -0,0 +1,206 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/arasan1289/hexagonal-demo/internal/adapters/config"
+	"github.com/arasan1289/hexagonal-demo/internal/adapters/logger"
+	"github.com/arasan1289/hexagonal-demo/internal/core/domain"
+	"github.com/arasan1289/hexagonal-demo/internal/core/port"
+	"github.com/arasan1289/hexagonal-demo/internal/core/util"
+)
+
+const (
+	// dataExportTTL is how long a built bundle can be downloaded
+	dataExportTTL = time.Hour * 24
+	// dataExportCooldown is how long another export can not be requested
+	dataExportCooldown = time.Hour
+	// dataExportKeyBytes is the number of random bytes of the export key, encoded it is a 32 byte AES-256 key
+	dataExportKeyBytes = 24
+
+	// dataExportPrefix prefixes the cache keys of the built bundles, keyed by the export ID
+	dataExportPrefix = "data_export:"
+	// dataExportPendingPrefix prefixes the cache keys marking a requested export, keyed by the user ID
+	dataExportPendingPrefix = "data_export_pending:"
+)
+
+// DataExportService struct represents the personal data export service with its dependencies
+type DataExportService struct {
+	repo        port.IUserRepository      // user repository interface
+	sessionRepo port.ISessionRepository   // session repository interface
+	outboxRepo  port.IOutboxRepository    // outbox the export requests are queued in
+	cache       port.ICache               // cache holding the built bundles
+	notifier    port.INotificationService // notification service delivering the download links
+	log         *logger.Logger            // logger instance
+	config      *config.App               // app configuration
+}
+
+// NewDataExportService constructor function
+func NewDataExportService(repo port.IUserRepository, sessionRepo port.ISessionRepository, outboxRepo port.IOutboxRepository, cache port.ICache, notifier port.INotificationService, log *logger.Logger, config *config.App) port.IDataExportService {
+	return &DataExportService{
+		repo:        repo,
+		sessionRepo: sessionRepo,
+		outboxRepo:  outboxRepo,
+		cache:       cache,
+		notifier:    notifier,
+		log:         log,
+		config:      config,
+	}
+}
+
+// RequestExport queues the export in the outbox, the outbox dispatcher builds it. The cooldown is claimed before
+// queueing, so parallel requests queue a single export, and released again when the export could not be queued.
+func (es *DataExportService) RequestExport(ctx context.Context, userID, locale string) error {
+	key := dataExportPendingPrefix + userID
+	claimed, err := es.cache.Increment(ctx, key, dataExportCooldown)
+	if err != nil {
+		return err
+	}
+	if claimed > 1 {
+		return domain.ErrExportPending
+	}
+
+	msg, err := newOutboxMessage(domain.OutboxTopicDataExport, domain.DataExportEvent{
+		ExportID: util.GenerateULID(),
+		UserID:   userID,
+		Locale:   locale,
+	})
+	if err == nil {
+		err = es.outboxRepo.CreateMessages(ctx, msg)
+	}
+	if err != nil {
+		if delErr := es.cache.Delete(ctx, key); delErr != nil {
+			es.log.Error().Err(delErr).Msg("Error releasing the data export cooldown")
+		}
+		return err
+	}
+	return nil
+}
+
+// BuildExport writes the profile, addresses and sessions of the user as JSON files into a ZIP bundle,
+// stores it under the export ID and sends the user a signed download link. Building the same export
+// again replaces the bundle, so failed deliveries can be retried by the outbox.
+func (es *DataExportService) BuildExport(ctx context.Context, event *domain.DataExportEvent) error {
+	// The user is read through the repository so the email and phone number are decrypted
+	user, err := es.repo.GetUser(ctx, event.UserID)
+	if err != nil {
+		return err
+	}
+	addresses, err := es.repo.ListAddresses(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	sessions, err := es.sessionRepo.ListSessions(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	files := []struct {
+		name string
+		data any
+	}{
+		{"profile.json", user},
+		{"addresses.json", addresses},
+		{"sessions.json", sessions},
+	}
+	for _, f := range files {
+		w, err := zw.Create(f.name)
+		if err != nil {
+			return err
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(f.data); err != nil {
+			return err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+
+	// The bundle is encrypted with a key only the download link carries, so the cached bundle can not be read
+	// without the link
+	key, err := util.GenerateToken(dataExportKeyBytes)
+	if err != nil {
+		return err
+	}
+	bundleEnc, err := util.EncryptString(buf.String(), key)
+	if err != nil {
+		return err
+	}
+	expiresAt := time.Now().Add(dataExportTTL)
+	if err := es.cache.Set(ctx, dataExportPrefix+event.ExportID, []byte(bundleEnc), dataExportTTL); err != nil {
+		return err
+	}
+
+	expires := strconv.FormatInt(expiresAt.Unix(), 10)
+	query := url.Values{
+		"id":        {event.ExportID},
+		"expires":   {expires},
+		"key":       {key},
+		"signature": {es.sign(event.ExportID, expires, key)},
+	}
+	data := map[string]any{
+		"app_name":     es.config.Name,
+		"first_name":   user.FirstName,
+		"link":         es.config.DataExportURL + "?" + query.Encode(),
+		"expiry_hours": int(dataExportTTL.Hours()),
+	}
+	// The link is emailed to verified emails, everyone else gets it by SMS
+	channel := domain.ChannelSMS
+	if user.Email != nil && user.IsEmailVerified {
+		channel = domain.ChannelEmail
+	}
+	if err := notifyUser(ctx, es.notifier, user, channel, domain.TemplateDataExport, event.Locale, data); err != nil {
+		return err
+	}
+	es.log.Info().Str("user_id", user.ID).Str("export_id", event.ExportID).Msg("Data export sent")
+	return nil
+}
+
+// DownloadExport checks the signature and expiry of the link, removes the bundle and decrypts it with the key of the link
+func (es *DataExportService) DownloadExport(ctx context.Context, id, expires, key, signature string) ([]byte, error) {
+	if !hmac.Equal([]byte(signature), []byte(es.sign(id, expires, key))) {
+		return nil, domain.ErrInvalidDownloadLink
+	}
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return nil, domain.ErrInvalidDownloadLink
+	}
+	if !time.Now().Before(time.Unix(expiresAt, 0)) {
+		return nil, domain.ErrDownloadLinkExpired
+	}
+
+	// Consume the bundle so the link can not be used twice, also by concurrent requests
+	bundleEnc, err := es.cache.GetDelete(ctx, dataExportPrefix+id)
+	if err != nil {
+		if errors.Is(err, domain.ErrDataNotFound) {
+			return nil, domain.ErrDownloadLinkExpired
+		}
+		return nil, err
+	}
+	bundle, err := util.DecryptString(string(bundleEnc), key)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(bundle), nil
+}
+
+// sign computes the signature binding the export ID to the expiry and key of the link
+func (es *DataExportService) sign(id, expires, key string) string {
+	mac := hmac.New(sha256.New, []byte(es.config.SecretKey))
+	mac.Write([]byte(id + ":" + expires + ":" + key))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
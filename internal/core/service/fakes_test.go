@@ -0,0 +1,311 @@
+package service
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/arasan1289/hexagonal-demo/internal/adapters/logger"
+	"github.com/arasan1289/hexagonal-demo/internal/core/domain"
+	"github.com/arasan1289/hexagonal-demo/internal/core/port"
+)
+
+// testLogger discards the log output of the services under test
+var testLogger = &logger.Logger{Logger: zerolog.Nop()}
+
+type fakeCacheEntry struct {
+	value     []byte
+	expiresAt time.Time
+}
+
+// fakeCache is an in-memory port.ICache, every operation is atomic like its Redis counterpart
+type fakeCache struct {
+	mu      sync.Mutex
+	entries map[string]fakeCacheEntry
+}
+
+func newFakeCache() *fakeCache {
+	return &fakeCache{entries: make(map[string]fakeCacheEntry)}
+}
+
+// get returns the live entry of the key, the lock must be held
+func (c *fakeCache) get(key string) ([]byte, bool) {
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if !e.expiresAt.IsZero() && !time.Now().Before(e.expiresAt) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	return e.value, true
+}
+
+// set stores the entry of the key, the lock must be held
+func (c *fakeCache) set(key string, value []byte, ttl time.Duration) {
+	e := fakeCacheEntry{value: append([]byte(nil), value...)}
+	if ttl > 0 {
+		e.expiresAt = time.Now().Add(ttl)
+	}
+	c.entries[key] = e
+}
+
+func (c *fakeCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.set(key, value, ttl)
+	return nil
+}
+
+func (c *fakeCache) Get(ctx context.Context, key string) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	val, ok := c.get(key)
+	if !ok {
+		return nil, domain.ErrDataNotFound
+	}
+	return val, nil
+}
+
+func (c *fakeCache) CompareAndSwap(ctx context.Context, key string, old, new []byte, ttl time.Duration) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	val, ok := c.get(key)
+	if !ok || string(val) != string(old) {
+		return false, nil
+	}
+	c.set(key, new, ttl)
+	return true, nil
+}
+
+func (c *fakeCache) GetDelete(ctx context.Context, key string) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	val, ok := c.get(key)
+	if !ok {
+		return nil, domain.ErrDataNotFound
+	}
+	delete(c.entries, key)
+	return val, nil
+}
+
+func (c *fakeCache) Increment(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var n int64
+	if val, ok := c.get(key); ok {
+		var err error
+		if n, err = strconv.ParseInt(string(val), 10, 64); err != nil {
+			return 0, err
+		}
+	}
+	n++
+	e := c.entries[key]
+	e.value = []byte(strconv.FormatInt(n, 10))
+	if n == 1 && ttl > 0 {
+		e.expiresAt = time.Now().Add(ttl)
+	}
+	c.entries[key] = e
+	return n, nil
+}
+
+func (c *fakeCache) Delete(ctx context.Context, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, key)
+	return nil
+}
+
+func (c *fakeCache) DeleteByPrefix(ctx context.Context, prefix string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key := range c.entries {
+		if strings.HasPrefix(key, prefix) {
+			delete(c.entries, key)
+		}
+	}
+	return nil
+}
+
+func (c *fakeCache) Close() error {
+	return nil
+}
+
+// fakeUserRepository keeps users and their ciphertexts in memory, the methods the services under test do not use
+// are left to the embedded interface
+type fakeUserRepository struct {
+	port.IUserRepository
+	mu     sync.Mutex
+	users  map[string]*domain.User
+	stored map[string]domain.UserCiphertexts
+}
+
+func (r *fakeUserRepository) GetUser(ctx context.Context, id string) (*domain.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	user, ok := r.users[id]
+	if !ok {
+		return nil, domain.ErrDataNotFound
+	}
+	cp := *user
+	return &cp, nil
+}
+
+func (r *fakeUserRepository) GetUserByPhoneNumberOrEmail(ctx context.Context, hashes ...string) (*domain.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var found *domain.User
+	for _, user := range r.users {
+		for _, hash := range hashes {
+			if user.PhoneNumberHash == hash || user.EmailHash != nil && *user.EmailHash == hash && user.IsEmailVerified {
+				found = user
+			}
+		}
+	}
+	if found == nil {
+		return nil, domain.ErrDataNotFound
+	}
+	cp := *found
+	return &cp, nil
+}
+
+func (r *fakeUserRepository) UpdateUser(ctx context.Context, user *domain.User, columns ...string) (*domain.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.users[user.ID]; !ok {
+		return nil, domain.ErrDataNotFound
+	}
+	cp := *user
+	r.users[user.ID] = &cp
+	return user, nil
+}
+
+func (r *fakeUserRepository) SetDataKey(ctx context.Context, id, wrapped string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c := r.stored[id]
+	if c.DataKey == nil {
+		c.DataKey = &wrapped
+		r.stored[id] = c
+	}
+	return *c.DataKey, nil
+}
+
+func (r *fakeUserRepository) SwapUserCiphertexts(ctx context.Context, id string, old, new *domain.UserCiphertexts) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored := r.stored[id]
+	// Compares the values like IS NOT DISTINCT FROM does
+	if !sameString(stored.DataKey, old.DataKey) ||
+		stored.PhoneNumberEncrypted != old.PhoneNumberEncrypted ||
+		stored.PhoneNumberHash != old.PhoneNumberHash ||
+		!sameString(stored.EmailEncrypted, old.EmailEncrypted) ||
+		!sameString(stored.EmailHash, old.EmailHash) ||
+		!sameString(stored.TOTPSecretEncrypted, old.TOTPSecretEncrypted) {
+		return false, nil
+	}
+	r.stored[id] = *new
+	return true, nil
+}
+
+func (r *fakeUserRepository) SwapRecoveryCodes(ctx context.Context, id, old, new string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	user, ok := r.users[id]
+	if !ok || user.TOTPRecoveryCodes == nil || *user.TOTPRecoveryCodes != old {
+		return false, nil
+	}
+	cp := *user
+	cp.TOTPRecoveryCodes = &new
+	r.users[id] = &cp
+	return true, nil
+}
+
+func sameString(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// fakeSessionRepository keeps the sessions in memory
+type fakeSessionRepository struct {
+	mu       sync.Mutex
+	sessions map[string]domain.Session
+}
+
+func newFakeSessionRepository() *fakeSessionRepository {
+	return &fakeSessionRepository{sessions: make(map[string]domain.Session)}
+}
+
+func (r *fakeSessionRepository) CreateSession(ctx context.Context, session *domain.Session) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sessions[session.ID] = *session
+	return nil
+}
+
+func (r *fakeSessionRepository) TouchSession(ctx context.Context, id string, lastSeenAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s, ok := r.sessions[id]
+	if !ok {
+		return domain.ErrDataNotFound
+	}
+	s.LastSeenAt = lastSeenAt
+	r.sessions[id] = s
+	return nil
+}
+
+func (r *fakeSessionRepository) ListSessions(ctx context.Context, userID string) ([]domain.Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var sessions []domain.Session
+	for _, s := range r.sessions {
+		if s.UserID == userID {
+			sessions = append(sessions, s)
+		}
+	}
+	return sessions, nil
+}
+
+func (r *fakeSessionRepository) DeleteSession(ctx context.Context, userID, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s, ok := r.sessions[id]
+	if !ok || s.UserID != userID {
+		return domain.ErrDataNotFound
+	}
+	delete(r.sessions, id)
+	return nil
+}
+
+func (r *fakeSessionRepository) DeleteUserSessions(ctx context.Context, userID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for id, s := range r.sessions {
+		if s.UserID == userID {
+			delete(r.sessions, id)
+		}
+	}
+	return nil
+}
+
+// fakeTokenKeySet has no asymmetric keys, tokens are signed with the shared JWT secret
+type fakeTokenKeySet struct{}
+
+func (fakeTokenKeySet) SigningKey() *domain.TokenKey {
+	return nil
+}
+
+func (fakeTokenKeySet) VerificationKey(kid string) (*domain.TokenKey, error) {
+	return nil, domain.ErrInvalidToken
+}
+
+func (fakeTokenKeySet) JWKS() *domain.JWKS {
+	return &domain.JWKS{}
+}
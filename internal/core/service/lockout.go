@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/arasan1289/hexagonal-demo/internal/core/domain"
+)
+
+const (
+	defaultLoginMaxAttempts = 5
+	defaultLoginLockout     = time.Minute
+	maxLoginLockout         = time.Hour * 24
+
+	// loginAttemptsTTL is how long the login attempts and lockouts of an account are counted after the first one
+	loginAttemptsTTL = time.Hour * 24
+
+	// loginAttemptsPrefix prefixes the cache keys counting the password logins since the last success or lockout,
+	// keyed by user ID so every identifier of the account shares the attempts
+	loginAttemptsPrefix = "login_attempts:"
+	// loginLockoutsPrefix prefixes the cache keys counting the consecutive lockouts, keyed by user ID
+	loginLockoutsPrefix = "login_lockouts:"
+	// loginLockedPrefix prefixes the cache keys marking locked accounts, keyed by user ID, they expire with the lockout
+	loginLockedPrefix = "login_locked:"
+)
+
+// reserveLoginAttempt counts the login attempt before the password is compared and returns its number. The
+// counter is incremented atomically, so parallel logins can not compare more passwords than the limit allows.
+// Returns ErrAccountLocked while the account is locked or when the attempts are used up.
+func (us *UserService) reserveLoginAttempt(ctx context.Context, userID string) (int64, error) {
+	_, err := us.cache.Get(ctx, loginLockedPrefix+userID)
+	if err == nil {
+		return 0, domain.ErrAccountLocked
+	}
+	if !errors.Is(err, domain.ErrDataNotFound) {
+		return 0, err
+	}
+
+	attempts, err := us.cache.Increment(ctx, loginAttemptsPrefix+userID, loginAttemptsTTL)
+	if err != nil {
+		return 0, err
+	}
+	if attempts > int64(us.loginMaxAttempts()) {
+		return 0, domain.ErrAccountLocked
+	}
+	return attempts, nil
+}
+
+// recordLoginFailure locks the account when the failed attempt was the last one allowed, every consecutive
+// lockout doubles the lockout duration. The attempts start over once the account is locked.
+func (us *UserService) recordLoginFailure(ctx context.Context, userID string, attempts int64) error {
+	if attempts < int64(us.loginMaxAttempts()) {
+		return nil
+	}
+
+	lockouts, err := us.cache.Increment(ctx, loginLockoutsPrefix+userID, loginAttemptsTTL)
+	if err != nil {
+		return err
+	}
+	lockout := us.lockoutDuration(uint(lockouts))
+	lockedUntil := time.Now().Add(lockout)
+	// The lock is set before the attempts are reset, so no attempt slips through in between
+	if err := us.cache.Set(ctx, loginLockedPrefix+userID, []byte(strconv.FormatInt(lockedUntil.Unix(), 10)), lockout); err != nil {
+		return err
+	}
+	us.log.Warn().Str("user_id", userID).Int64("lockouts", lockouts).Time("locked_until", lockedUntil).Msg("Account locked after failed logins")
+	return us.cache.Delete(ctx, loginAttemptsPrefix+userID)
+}
+
+// resetLoginFailures clears the login attempts and lockouts of the account
+func (us *UserService) resetLoginFailures(ctx context.Context, userID string) error {
+	for _, prefix := range []string{loginAttemptsPrefix, loginLockoutsPrefix, loginLockedPrefix} {
+		if err := us.cache.Delete(ctx, prefix+userID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (us *UserService) loginMaxAttempts() uint {
+	if us.config.LoginMaxAttempts == 0 {
+		return defaultLoginMaxAttempts
+	}
+	return us.config.LoginMaxAttempts
+}
+
+func (us *UserService) lockoutDuration(lockouts uint) time.Duration {
+	base := defaultLoginLockout
+	if us.config.LoginLockout != 0 {
+		base = time.Duration(us.config.LoginLockout) * time.Second
+	}
+	d := float64(base) * math.Pow(2, float64(lockouts-1))
+	if d > float64(maxLoginLockout) {
+		return maxLoginLockout
+	}
+	return time.Duration(d)
+}
@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/arasan1289/hexagonal-demo/internal/adapters/config"
+	"github.com/arasan1289/hexagonal-demo/internal/core/domain"
+)
+
+const (
+	testEmail    = "example@example.com"
+	testPassword = "correct horse battery staple"
+)
+
+// newTestUserService returns a user service with one user "user-1" who logs in with the test phone number or email
+func newTestUserService(t *testing.T) *UserService {
+	conf := &config.App{BlindIndexKey: "blind-index-key", BlindIndexBackfilled: true, LoginMaxAttempts: 3}
+	hash, err := generatePasswordHash(testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	emailHash := blindIndex(conf, testEmail)
+	email := testEmail
+	repo := &fakeUserRepository{users: map[string]*domain.User{
+		"user-1": {
+			BaseModel:       domain.BaseModel{ID: "user-1"},
+			PhoneNumber:     testPhoneNumber,
+			PhoneNumberHash: blindIndex(conf, testPhoneNumber),
+			Email:           &email,
+			EmailHash:       &emailHash,
+			IsEmailVerified: true,
+			Password:        &hash,
+			IsActive:        true,
+		},
+	}}
+	return &UserService{repo: repo, cache: newFakeCache(), log: testLogger, config: conf}
+}
+
+func TestGetUserAndComparePasswordLockout(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("locks after the last allowed failure", func(t *testing.T) {
+		us := newTestUserService(t)
+		for i := 0; i < int(us.loginMaxAttempts()); i++ {
+			if _, match, err := us.GetUserAndComparePassword(ctx, testPhoneNumber, "wrong"); err != nil || match {
+				t.Fatalf("GetUserAndComparePassword() failure %d = (%v, %v), want (false, nil)", i+1, match, err)
+			}
+		}
+		// The correct password is refused while the account is locked
+		if _, _, err := us.GetUserAndComparePassword(ctx, testPhoneNumber, testPassword); !errors.Is(err, domain.ErrAccountLocked) {
+			t.Fatalf("GetUserAndComparePassword() of a locked account error = %v, want %v", err, domain.ErrAccountLocked)
+		}
+
+		if err := us.UnlockUser(ctx, "user-1"); err != nil {
+			t.Fatal(err)
+		}
+		if _, match, err := us.GetUserAndComparePassword(ctx, testPhoneNumber, testPassword); err != nil || !match {
+			t.Errorf("GetUserAndComparePassword() after unlocking = (%v, %v), want (true, nil)", match, err)
+		}
+	})
+
+	t.Run("success resets the failures", func(t *testing.T) {
+		us := newTestUserService(t)
+		for round := 0; round < 2; round++ {
+			for i := 0; i < int(us.loginMaxAttempts())-1; i++ {
+				if _, _, err := us.GetUserAndComparePassword(ctx, testPhoneNumber, "wrong"); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if _, match, err := us.GetUserAndComparePassword(ctx, testPhoneNumber, testPassword); err != nil || !match {
+				t.Fatalf("GetUserAndComparePassword() round %d = (%v, %v), want (true, nil)", round+1, match, err)
+			}
+		}
+	})
+
+	t.Run("identifiers share the attempts", func(t *testing.T) {
+		us := newTestUserService(t)
+		for i := 0; i < int(us.loginMaxAttempts()); i++ {
+			identifier := testPhoneNumber
+			if i%2 == 1 {
+				identifier = testEmail
+			}
+			if _, _, err := us.GetUserAndComparePassword(ctx, identifier, "wrong"); err != nil {
+				t.Fatal(err)
+			}
+		}
+		for _, identifier := range []string{testPhoneNumber, testEmail} {
+			if _, _, err := us.GetUserAndComparePassword(ctx, identifier, testPassword); !errors.Is(err, domain.ErrAccountLocked) {
+				t.Errorf("GetUserAndComparePassword(%q) error = %v, want %v", identifier, err, domain.ErrAccountLocked)
+			}
+		}
+	})
+}
+
+func TestGetUserAndComparePasswordConcurrent(t *testing.T) {
+	ctx := context.Background()
+	us := newTestUserService(t)
+
+	const guesses = 10
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var compared, locked int
+	for i := 0; i < guesses; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _, err := us.GetUserAndComparePassword(ctx, testPhoneNumber, "wrong")
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				compared++
+			case errors.Is(err, domain.ErrAccountLocked):
+				locked++
+			default:
+				t.Errorf("GetUserAndComparePassword() unexpected error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if max := int(us.loginMaxAttempts()); compared != max {
+		t.Errorf("%d parallel guesses were compared, want %d", compared, max)
+	}
+	if compared+locked != guesses {
+		t.Errorf("got %d compared and %d locked, want %d guesses", compared, locked, guesses)
+	}
+}
@@ -0,0 +1,162 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/arasan1289/hexagonal-demo/internal/adapters/config"
+	"github.com/arasan1289/hexagonal-demo/internal/adapters/logger"
+	"github.com/arasan1289/hexagonal-demo/internal/core/domain"
+	"github.com/arasan1289/hexagonal-demo/internal/core/port"
+	"github.com/arasan1289/hexagonal-demo/internal/core/util"
+)
+
+const (
+	magicLinkTTL = time.Minute * 15
+	// magicLinkRetention is how long a used or expired link is remembered to tell it apart from an invalid one
+	magicLinkRetention = time.Hour * 24
+
+	// magicLinkPrefix prefixes the cache keys of the magic link states, keyed by the hash of the token
+	magicLinkPrefix = "magic_link:"
+)
+
+// MagicLinkService struct represents the magic link login service with its dependencies
+type MagicLinkService struct {
+	repo     port.IUserRepository      // user repository interface
+	cache    port.ICache               // cache holding the issued links
+	notifier port.INotificationService // notification service delivering the links
+	log      *logger.Logger            // logger instance
+	config   *config.App               // app configuration
+}
+
+// NewMagicLinkService constructor function
+func NewMagicLinkService(repo port.IUserRepository, cache port.ICache, notifier port.INotificationService, log *logger.Logger, config *config.App) port.IMagicLinkService {
+	return &MagicLinkService{
+		repo:     repo,
+		cache:    cache,
+		notifier: notifier,
+		log:      log,
+		config:   config,
+	}
+}
+
+// SendMagicLink emails a login link whose token is signed together with the email hash.
+// Unknown and unverified emails are ignored so the response does not reveal which accounts exist.
+func (ms *MagicLinkService) SendMagicLink(ctx context.Context, email, locale string) error {
+	user, err := ms.repo.GetUserByPhoneNumberOrEmail(ctx, blindIndexes(ms.config, email)...)
+	if err != nil {
+		if errors.Is(err, domain.ErrDataNotFound) {
+			ms.log.Info().Msg("Magic link requested for unknown email")
+			return nil
+		}
+		return err
+	}
+	if !matchesBlindIndex(ms.config, user.EmailHash, email) || !user.IsEmailVerified {
+		ms.log.Info().Str("user_id", user.ID).Msg("Magic link requested for unverified email")
+		return nil
+	}
+
+	raw, err := util.GenerateToken(32)
+	if err != nil {
+		return err
+	}
+	emailHash := blindIndex(ms.config, email)
+	state := domain.MagicLinkState{
+		UserID:    user.ID,
+		EmailHash: emailHash,
+		ExpiresAt: time.Now().Add(magicLinkTTL),
+	}
+	if err := ms.setState(ctx, raw, &state); err != nil {
+		return err
+	}
+
+	token := raw + "." + ms.sign(raw, emailHash)
+	data := map[string]any{
+		"app_name":       ms.config.Name,
+		"first_name":     user.FirstName,
+		"link":           ms.config.MagicLinkURL + "?token=" + url.QueryEscape(token),
+		"expiry_minutes": int(magicLinkTTL.Minutes()),
+	}
+	return notifyUser(ctx, ms.notifier, user, domain.ChannelEmail, domain.TemplateMagicLink, locale, data)
+}
+
+// VerifyMagicLink checks the signature of the token against the email it was sent to and marks it used
+func (ms *MagicLinkService) VerifyMagicLink(ctx context.Context, token string) (*domain.User, error) {
+	raw, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, domain.ErrInvalidMagicLink
+	}
+	val, err := ms.cache.Get(ctx, magicLinkPrefix+util.HashString(raw))
+	if err != nil {
+		if errors.Is(err, domain.ErrDataNotFound) {
+			return nil, domain.ErrInvalidMagicLink
+		}
+		return nil, err
+	}
+	var state domain.MagicLinkState
+	if err := json.Unmarshal(val, &state); err != nil {
+		return nil, err
+	}
+
+	if !hmac.Equal([]byte(sig), []byte(ms.sign(raw, state.EmailHash))) {
+		return nil, domain.ErrInvalidMagicLink
+	}
+	if state.Used {
+		ms.log.Warn().Msg("Magic link reused")
+		return nil, domain.ErrMagicLinkUsed
+	}
+	if !time.Now().Before(state.ExpiresAt) {
+		return nil, domain.ErrMagicLinkExpired
+	}
+
+	// Consume the link before logging in, swapping the state read above so concurrent uses of the link
+	// can not all succeed
+	state.Used = true
+	used, err := json.Marshal(&state)
+	if err != nil {
+		return nil, err
+	}
+	ttl := time.Until(state.ExpiresAt.Add(magicLinkRetention))
+	swapped, err := ms.cache.CompareAndSwap(ctx, magicLinkPrefix+util.HashString(raw), val, used, ttl)
+	if err != nil {
+		return nil, err
+	}
+	if !swapped {
+		ms.log.Warn().Msg("Magic link used concurrently")
+		return nil, domain.ErrMagicLinkUsed
+	}
+
+	user, err := ms.repo.GetUser(ctx, state.UserID)
+	if err != nil {
+		return nil, err
+	}
+	// The email may have been changed since the link was sent
+	if user.Email == nil || blindIndex(ms.config, *user.Email) != state.EmailHash || !user.IsEmailVerified {
+		return nil, domain.ErrInvalidMagicLink
+	}
+	return user, nil
+}
+
+// sign computes the signature binding the token to the email hash
+func (ms *MagicLinkService) sign(raw, emailHash string) string {
+	mac := hmac.New(sha256.New, []byte(ms.config.SecretKey))
+	mac.Write([]byte(raw + ":" + emailHash))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// setState stores the link state until the retention after its expiry has passed
+func (ms *MagicLinkService) setState(ctx context.Context, raw string, state *domain.MagicLinkState) error {
+	val, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	ttl := time.Until(state.ExpiresAt.Add(magicLinkRetention))
+	return ms.cache.Set(ctx, magicLinkPrefix+util.HashString(raw), val, ttl)
+}
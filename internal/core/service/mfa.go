@@ -0,0 +1,278 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base32"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/arasan1289/hexagonal-demo/internal/adapters/config"
+	"github.com/arasan1289/hexagonal-demo/internal/adapters/logger"
+	"github.com/arasan1289/hexagonal-demo/internal/core/domain"
+	"github.com/arasan1289/hexagonal-demo/internal/core/port"
+	"github.com/arasan1289/hexagonal-demo/internal/core/util"
+)
+
+const (
+	recoveryCodeCount = 10
+	mfaMaxAttempts    = 5
+	// mfaLockout is the window the attempts of a user are counted in, MFA stays locked until it ends
+	mfaLockout = time.Minute * 15
+
+	// mfaAttemptsPrefix prefixes the cache keys counting the codes tried by a user, keyed by user ID
+	mfaAttemptsPrefix = "mfa_attempts:"
+	// mfaTokenPrefix prefixes the cache keys claiming an MFA token while its code is checked, keyed by token ID
+	mfaTokenPrefix = "mfa_token:"
+	// totpStepPrefix prefixes the cache keys holding the last accepted TOTP time step of a user
+	totpStepPrefix = "totp_step:"
+)
+
+// MFAService struct represents the two-factor authentication service with its dependencies
+type MFAService struct {
+	repo    port.IUserRepository // user repository interface
+	authSvc port.IAuthService    // auth service verifying and revoking MFA tokens
+	keys    port.IKeyManager     // key manager wrapping the data keys of users
+	cache   port.ICache          // cache holding attempt counters and used TOTP steps
+	log     *logger.Logger       // logger instance
+	config  *config.App          // app configuration
+}
+
+// NewMFAService constructor function
+func NewMFAService(repo port.IUserRepository, authSvc port.IAuthService, keys port.IKeyManager, cache port.ICache, log *logger.Logger, config *config.App) port.IMFAService {
+	return &MFAService{
+		repo:    repo,
+		authSvc: authSvc,
+		keys:    keys,
+		cache:   cache,
+		log:     log,
+		config:  config,
+	}
+}
+
+// EnrollTOTP generates and stores an encrypted TOTP secret, enrolling again replaces an unconfirmed secret
+func (ms *MFAService) EnrollTOTP(ctx context.Context, userID string) (*domain.TOTPEnrollment, error) {
+	user, err := ms.repo.GetUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.IsTOTPEnabled {
+		return nil, domain.ErrMFAAlreadyEnabled
+	}
+
+	secret, err := util.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	secretEnc, err := encryptUserData(ctx, ms.repo, ms.keys, user, secret)
+	if err != nil {
+		return nil, err
+	}
+	user.TOTPSecretEncrypted = &secretEnc
+	if _, err := ms.repo.UpdateUser(ctx, user, "totp_secret_encrypted"); err != nil {
+		return nil, err
+	}
+
+	account := user.PhoneNumber
+	if user.Email != nil {
+		account = *user.Email
+	}
+	return &domain.TOTPEnrollment{
+		Secret: secret,
+		URI:    util.TOTPURI(ms.config.Name, account, secret),
+	}, nil
+}
+
+// ConfirmTOTP enables TOTP when the code matches the enrolled secret and generates new recovery codes
+func (ms *MFAService) ConfirmTOTP(ctx context.Context, userID, code string) (*domain.RecoveryCodes, error) {
+	user, err := ms.repo.GetUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.IsTOTPEnabled {
+		return nil, domain.ErrMFAAlreadyEnabled
+	}
+	if user.TOTPSecretEncrypted == nil {
+		return nil, domain.ErrMFANotEnrolled
+	}
+
+	valid, err := ms.verifyTOTP(ctx, user, code)
+	if err != nil {
+		return nil, err
+	}
+	if !valid {
+		return nil, domain.ErrInvalidMFACode
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	user.IsTOTPEnabled = true
+	user.TOTPRecoveryCodes = &hashes
+	if _, err := ms.repo.UpdateUser(ctx, user, "is_totp_enabled", "totp_recovery_codes"); err != nil {
+		return nil, err
+	}
+	return &domain.RecoveryCodes{Codes: codes}, nil
+}
+
+// VerifyLogin exchanges the MFA token and a TOTP or recovery code for a token pair marked as logged in with a
+// second factor, the token is revoked on success. Attempts are counted per user across logins, after too many
+// attempts MFA is locked until the window ends.
+func (ms *MFAService) VerifyLogin(ctx context.Context, mfaToken, code string, client *domain.ClientInfo) (*domain.JWTToken, error) {
+	claims, err := ms.authSvc.VerifyMFAToken(ctx, mfaToken)
+	if err != nil {
+		return nil, err
+	}
+
+	// Counting before checking the code keeps parallel guesses within the limit
+	attemptsKey := mfaAttemptsPrefix + claims.Subject
+	attempts, err := ms.cache.Increment(ctx, attemptsKey, mfaLockout)
+	if err != nil {
+		return nil, err
+	}
+	if attempts > mfaMaxAttempts {
+		return nil, domain.ErrMFAAttemptsExceeded
+	}
+
+	// The token is claimed before the code is checked, so parallel requests can not exchange it twice.
+	// The claim is released when the code is rejected and kept once the token is used.
+	tokenKey := mfaTokenPrefix + claims.ID
+	claimed, err := ms.cache.Increment(ctx, tokenKey, mfaTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+	if claimed > 1 {
+		return nil, domain.ErrRevokedToken
+	}
+
+	user, err := ms.verifyLoginCode(ctx, claims.Subject, code, attempts)
+	if err != nil {
+		if delErr := ms.cache.Delete(ctx, tokenKey); delErr != nil {
+			return nil, delErr
+		}
+		return nil, err
+	}
+
+	if err := ms.cache.Delete(ctx, attemptsKey); err != nil {
+		return nil, err
+	}
+	if err := ms.authSvc.RevokeJWT(ctx, claims); err != nil {
+		return nil, err
+	}
+	return ms.authSvc.GenerateJWT(ctx, user, client, true)
+}
+
+// verifyLoginCode checks the TOTP or recovery code of the user, attempts is the number of the attempt being checked
+func (ms *MFAService) verifyLoginCode(ctx context.Context, userID, code string, attempts int64) (*domain.User, error) {
+	user, err := ms.repo.GetUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.IsTOTPEnabled {
+		return nil, domain.ErrMFANotEnrolled
+	}
+
+	valid, err := ms.verifyTOTP(ctx, user, code)
+	if err != nil {
+		return nil, err
+	}
+	if !valid {
+		valid, err = ms.useRecoveryCode(ctx, user, code)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !valid {
+		if attempts == mfaMaxAttempts {
+			ms.log.Warn().Str("user_id", user.ID).Msg("MFA locked after incorrect codes")
+			return nil, domain.ErrMFAAttemptsExceeded
+		}
+		return nil, domain.ErrInvalidMFACode
+	}
+	return user, nil
+}
+
+// verifyTOTP checks the code against the user's TOTP secret, a code is only accepted once
+func (ms *MFAService) verifyTOTP(ctx context.Context, user *domain.User, code string) (bool, error) {
+	secret, err := decryptUserData(ctx, ms.keys, user, *user.TOTPSecretEncrypted, ms.config.SecretKeys())
+	if err != nil {
+		return false, err
+	}
+	step, valid, err := util.VerifyTOTP(secret, code, time.Now())
+	if err != nil || !valid {
+		return false, err
+	}
+
+	stepKey := totpStepPrefix + user.ID
+	lastStep, err := ms.getCounter(ctx, stepKey)
+	if err != nil {
+		return false, err
+	}
+	if step <= int64(lastStep) {
+		return false, nil
+	}
+	// The accepted steps around now expire within a few periods
+	return true, ms.cache.Set(ctx, stepKey, []byte(strconv.FormatInt(step, 10)), 4*util.TOTPPeriod)
+}
+
+// useRecoveryCode consumes the recovery code when it is one of the user's unused codes. The codes are only
+// replaced if nobody changed them since they were read, so a code used concurrently is accepted once.
+func (ms *MFAService) useRecoveryCode(ctx context.Context, user *domain.User, code string) (bool, error) {
+	if user.TOTPRecoveryCodes == nil {
+		return false, nil
+	}
+	codeHash := util.HashString(normalizeRecoveryCode(code))
+	hashes := strings.Split(*user.TOTPRecoveryCodes, ",")
+	for i, h := range hashes {
+		if subtle.ConstantTimeCompare([]byte(h), []byte(codeHash)) != 1 {
+			continue
+		}
+		remaining := strings.Join(append(hashes[:i:i], hashes[i+1:]...), ",")
+		swapped, err := ms.repo.SwapRecoveryCodes(ctx, user.ID, *user.TOTPRecoveryCodes, remaining)
+		if err != nil || !swapped {
+			return false, err
+		}
+		user.TOTPRecoveryCodes = &remaining
+		ms.log.Info().Str("user_id", user.ID).Int("remaining", len(hashes)-1).Msg("Recovery code used")
+		return true, nil
+	}
+	return false, nil
+}
+
+// getCounter reads an integer counter from the cache, missing counters are zero
+func (ms *MFAService) getCounter(ctx context.Context, key string) (int, error) {
+	val, err := ms.cache.Get(ctx, key)
+	if err != nil {
+		if errors.Is(err, domain.ErrDataNotFound) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return strconv.Atoi(string(val))
+}
+
+// generateRecoveryCodes returns new recovery codes and their comma separated hashes
+func generateRecoveryCodes() ([]string, string, error) {
+	encoding := base32.StdEncoding.WithPadding(base32.NoPadding)
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		b := make([]byte, 5)
+		if _, err := rand.Read(b); err != nil {
+			return nil, "", err
+		}
+		code := strings.ToLower(encoding.EncodeToString(b))
+		codes[i] = code[:4] + "-" + code[4:]
+		hashes[i] = util.HashString(code)
+	}
+	return codes, strings.Join(hashes, ","), nil
+}
+
+// normalizeRecoveryCode strips the formatting users may type recovery codes with
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
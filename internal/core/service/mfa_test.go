@@ -0,0 +1,206 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/arasan1289/hexagonal-demo/internal/adapters/config"
+	"github.com/arasan1289/hexagonal-demo/internal/core/domain"
+	"github.com/arasan1289/hexagonal-demo/internal/core/util"
+)
+
+const testTOTPSecret = "JBSWY3DPEHPK3PXP"
+
+// newTestMFAService returns an MFA service whose user "user-1" has TOTP enabled with the returned recovery codes
+func newTestMFAService(t *testing.T) (*MFAService, *AuthService, []string) {
+	as, cache, users := newTestAuthService()
+	conf := &config.App{SecretKey: testSecretKey}
+	secretEnc, err := util.EncryptString(testTOTPSecret, testSecretKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := users.users["user-1"]
+	user.IsTOTPEnabled = true
+	user.TOTPSecretEncrypted = &secretEnc
+	user.TOTPRecoveryCodes = &hashes
+
+	ms := &MFAService{repo: users, authSvc: as, cache: cache, log: testLogger, config: conf}
+	return ms, as, codes
+}
+
+// currentTOTP returns the TOTP code of the test secret for now
+func currentTOTP(t *testing.T) string {
+	code, err := util.TOTPCode(testTOTPSecret, time.Now().Unix()/int64(util.TOTPPeriod.Seconds()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return code
+}
+
+// newMFAToken returns an MFA token of "user-1" as issued after the password login
+func newMFAToken(t *testing.T, as *AuthService) string {
+	ctx := context.Background()
+	user, err := as.userRepo.GetUser(ctx, "user-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mfaToken, err := as.GenerateMFAToken(ctx, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return mfaToken
+}
+
+func TestVerifyLogin(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("TOTP marks the token family", func(t *testing.T) {
+		ms, as, _ := newTestMFAService(t)
+		mfaToken := newMFAToken(t, as)
+		tokens, err := ms.VerifyLogin(ctx, mfaToken, currentTOTP(t), nil)
+		if err != nil {
+			t.Fatalf("VerifyLogin() error = %v", err)
+		}
+		claims, err := as.VerifyJWT(ctx, tokens.AccessToken)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !claims.MFA {
+			t.Error("access token is not marked as logged in with a second factor")
+		}
+
+		// Refreshed tokens carry the flag of the family
+		refreshed, err := as.RefreshJWT(ctx, tokens.RefreshToken)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if claims, err = as.VerifyJWT(ctx, refreshed.AccessToken); err != nil || !claims.MFA {
+			t.Errorf("VerifyJWT() of the refreshed access token = (mfa %v, %v), want (mfa true, nil)", claims != nil && claims.MFA, err)
+		}
+
+		// The MFA token is used once
+		if _, err := ms.VerifyLogin(ctx, mfaToken, currentTOTP(t), nil); !errors.Is(err, domain.ErrRevokedToken) {
+			t.Errorf("VerifyLogin() with a used MFA token error = %v, want %v", err, domain.ErrRevokedToken)
+		}
+	})
+
+	t.Run("login without second factor", func(t *testing.T) {
+		_, as, _ := newTestMFAService(t)
+		user, _ := as.userRepo.GetUser(ctx, "user-1")
+		// Enabling TOTP alone does not mark the tokens of a login which skipped it
+		tokens, err := as.GenerateJWT(ctx, user, nil, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		refreshed, err := as.RefreshJWT(ctx, tokens.RefreshToken)
+		if err != nil {
+			t.Fatal(err)
+		}
+		claims, err := as.VerifyJWT(ctx, refreshed.AccessToken)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if claims.MFA {
+			t.Error("access token of a login without second factor is marked as logged in with one")
+		}
+	})
+
+	t.Run("incorrect code keeps the MFA token", func(t *testing.T) {
+		ms, as, _ := newTestMFAService(t)
+		mfaToken := newMFAToken(t, as)
+		if _, err := ms.VerifyLogin(ctx, mfaToken, "invalid", nil); !errors.Is(err, domain.ErrInvalidMFACode) {
+			t.Fatalf("VerifyLogin() error = %v, want %v", err, domain.ErrInvalidMFACode)
+		}
+		if _, err := ms.VerifyLogin(ctx, mfaToken, currentTOTP(t), nil); err != nil {
+			t.Errorf("VerifyLogin() after an incorrect code error = %v", err)
+		}
+	})
+
+	t.Run("recovery code is used once", func(t *testing.T) {
+		ms, as, codes := newTestMFAService(t)
+		if _, err := ms.VerifyLogin(ctx, newMFAToken(t, as), codes[0], nil); err != nil {
+			t.Fatalf("VerifyLogin() error = %v", err)
+		}
+		if _, err := ms.VerifyLogin(ctx, newMFAToken(t, as), codes[0], nil); !errors.Is(err, domain.ErrInvalidMFACode) {
+			t.Errorf("VerifyLogin() with a used recovery code error = %v, want %v", err, domain.ErrInvalidMFACode)
+		}
+		if _, err := ms.VerifyLogin(ctx, newMFAToken(t, as), codes[1], nil); err != nil {
+			t.Errorf("VerifyLogin() with another recovery code error = %v", err)
+		}
+	})
+
+	t.Run("attempts exhausted", func(t *testing.T) {
+		ms, as, codes := newTestMFAService(t)
+		for i := 0; i < mfaMaxAttempts; i++ {
+			want := domain.ErrInvalidMFACode
+			if i == mfaMaxAttempts-1 {
+				want = domain.ErrMFAAttemptsExceeded
+			}
+			if _, err := ms.VerifyLogin(ctx, newMFAToken(t, as), "invalid", nil); !errors.Is(err, want) {
+				t.Fatalf("VerifyLogin() attempt %d error = %v, want %v", i+1, err, want)
+			}
+		}
+		if _, err := ms.VerifyLogin(ctx, newMFAToken(t, as), codes[0], nil); !errors.Is(err, domain.ErrMFAAttemptsExceeded) {
+			t.Errorf("VerifyLogin() after the attempts error = %v, want %v", err, domain.ErrMFAAttemptsExceeded)
+		}
+	})
+}
+
+func TestVerifyLoginConcurrent(t *testing.T) {
+	ctx := context.Background()
+	// Stays within the attempts, so only the single use of tokens and codes refuses requests
+	const requests = mfaMaxAttempts
+
+	run := func(verify func(i int) error) int {
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		var succeeded int
+		for i := 0; i < requests; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				if err := verify(i); err == nil {
+					mu.Lock()
+					succeeded++
+					mu.Unlock()
+				}
+			}(i)
+		}
+		wg.Wait()
+		return succeeded
+	}
+
+	t.Run("same recovery code", func(t *testing.T) {
+		ms, as, codes := newTestMFAService(t)
+		mfaTokens := make([]string, requests)
+		for i := range mfaTokens {
+			mfaTokens[i] = newMFAToken(t, as)
+		}
+		succeeded := run(func(i int) error {
+			_, err := ms.VerifyLogin(ctx, mfaTokens[i], codes[0], nil)
+			return err
+		})
+		if succeeded != 1 {
+			t.Errorf("the same recovery code was accepted %d times, want 1", succeeded)
+		}
+	})
+
+	t.Run("same MFA token", func(t *testing.T) {
+		ms, as, codes := newTestMFAService(t)
+		mfaToken := newMFAToken(t, as)
+		succeeded := run(func(i int) error {
+			_, err := ms.VerifyLogin(ctx, mfaToken, codes[i], nil)
+			return err
+		})
+		if succeeded != 1 {
+			t.Errorf("the same MFA token was exchanged %d times, want 1", succeeded)
+		}
+	})
+}
@@ -0,0 +1,63 @@
+package service
+
+import (
+	"context"
+
+	"github.com/arasan1289/hexagonal-demo/internal/core/domain"
+	"github.com/arasan1289/hexagonal-demo/internal/core/port"
+)
+
+// notificationRecipient resolves the address of the user on the given channel
+func notificationRecipient(user *domain.User, channel domain.NotificationChannel) (string, error) {
+	switch channel {
+	case domain.ChannelSMS, domain.ChannelWhatsapp:
+		return user.PhoneNumber, nil
+	case domain.ChannelEmail:
+		if user.Email == nil || *user.Email == "" {
+			return "", domain.ErrEmailNotSet
+		}
+		return *user.Email, nil
+	case domain.ChannelPush:
+		return user.ID, nil
+	default:
+		return "", domain.ErrUnsupportedChannel
+	}
+}
+
+// notifyUser renders the template and delivers it to the user through the given channel.
+// Without an explicit locale, the user's preferred locale is used.
+func notifyUser(ctx context.Context, ns port.INotificationService, user *domain.User, channel domain.NotificationChannel, template domain.TemplateID, locale string, data map[string]any) error {
+	to, err := notificationRecipient(user, channel)
+	if err != nil {
+		return err
+	}
+	if locale == "" {
+		locale = user.Locale
+	}
+	return sendNotification(ctx, ns, channel, to, template, locale, data)
+}
+
+// sendNotification renders the template and delivers it to the recipient through the given channel
+func sendNotification(ctx context.Context, ns port.INotificationService, channel domain.NotificationChannel, to string, template domain.TemplateID, locale string, data map[string]any) error {
+	var sent bool
+	var err error
+	switch channel {
+	case domain.ChannelSMS:
+		sent, err = ns.SendSMS(ctx, to, template, locale, data)
+	case domain.ChannelWhatsapp:
+		sent, err = ns.SendWhatsapp(ctx, to, template, locale, data)
+	case domain.ChannelEmail:
+		sent, err = ns.SendEmail(ctx, to, template, locale, data)
+	case domain.ChannelPush:
+		sent, err = ns.SendPush(ctx, to, template, locale, data)
+	default:
+		return domain.ErrUnsupportedChannel
+	}
+	if err != nil {
+		return err
+	}
+	if !sent {
+		return domain.ErrNotificationFailed
+	}
+	return nil
+}
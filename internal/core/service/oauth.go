@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/arasan1289/hexagonal-demo/internal/adapters/logger"
+	"github.com/arasan1289/hexagonal-demo/internal/core/domain"
+	"github.com/arasan1289/hexagonal-demo/internal/core/port"
+	"github.com/arasan1289/hexagonal-demo/internal/core/util"
+)
+
+// OAuthService struct represents the OAuth authorization server with its dependencies
+type OAuthService struct {
+	repo    port.IOAuthClientRepository // OAuth client repository interface
+	authSvc port.IAuthService           // auth service issuing the tokens
+	log     *logger.Logger              // logger instance
+}
+
+// NewOAuthService constructor function
+func NewOAuthService(repo port.IOAuthClientRepository, authSvc port.IAuthService, log *logger.Logger) port.IOAuthService {
+	return &OAuthService{
+		repo:    repo,
+		authSvc: authSvc,
+		log:     log,
+	}
+}
+
+// RegisterClient generates a client secret and stores its argon2 hash, every scope must be a defined permission
+func (os *OAuthService) RegisterClient(ctx context.Context, name string, scopes []string, createdBy string) (*domain.NewOAuthClient, error) {
+	for _, scope := range scopes {
+		if !domain.Permission(scope).IsValid() {
+			return nil, domain.ErrInvalidScope
+		}
+	}
+
+	secret, err := util.GenerateToken(32)
+	if err != nil {
+		return nil, err
+	}
+	secretHash, err := generatePasswordHash(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	client := &domain.OAuthClient{
+		BaseModel:  domain.BaseModel{ID: util.GenerateULID()},
+		Name:       name,
+		SecretHash: secretHash,
+		Scopes:     joinScopes(scopes),
+		CreatedBy:  createdBy,
+	}
+	if err := os.repo.CreateClient(ctx, client); err != nil {
+		return nil, err
+	}
+	os.log.Info().Str("client_id", client.ID).Str("created_by", createdBy).Msg("OAuth client registered")
+
+	return &domain.NewOAuthClient{Client: client, ClientID: client.ID, ClientSecret: secret}, nil
+}
+
+// RevokeClient marks the OAuth client revoked and revokes the tokens already issued to it
+func (os *OAuthService) RevokeClient(ctx context.Context, id string) error {
+	now := time.Now()
+	client := &domain.OAuthClient{BaseModel: domain.BaseModel{ID: id}, RevokedAt: &now}
+	if err := os.repo.UpdateClient(ctx, client, "revoked_at"); err != nil {
+		return err
+	}
+	os.log.Info().Str("client_id", id).Msg("OAuth client revoked")
+	return os.authSvc.RevokeAllJWT(ctx, id)
+}
+
+// AuthenticateClient returns the client when it exists, is not revoked and the secret matches
+func (os *OAuthService) AuthenticateClient(ctx context.Context, clientID, clientSecret string) (*domain.OAuthClient, error) {
+	if clientID == "" || clientSecret == "" {
+		return nil, domain.ErrInvalidClient
+	}
+	client, err := os.repo.GetClient(ctx, clientID)
+	if err != nil {
+		if errors.Is(err, domain.ErrDataNotFound) {
+			return nil, domain.ErrInvalidClient
+		}
+		return nil, err
+	}
+	if client.RevokedAt != nil {
+		return nil, domain.ErrInvalidClient
+	}
+
+	match, err := comparePasswordAndHash(clientSecret, client.SecretHash)
+	if err != nil {
+		return nil, err
+	}
+	if !match {
+		return nil, domain.ErrInvalidClient
+	}
+	return client, nil
+}
+
+// ClientCredentials grants the requested scopes when the client is allowed all of them
+func (os *OAuthService) ClientCredentials(ctx context.Context, client *domain.OAuthClient, scope string) (*domain.OAuthToken, error) {
+	allowed := client.ScopeList()
+	granted := strings.Fields(scope)
+	if len(granted) == 0 {
+		granted = allowed
+	}
+	for _, s := range granted {
+		if !containsString(allowed, s) {
+			return nil, domain.ErrInvalidScope
+		}
+	}
+
+	token, err := os.authSvc.GenerateClientJWT(ctx, client, granted)
+	if err != nil {
+		return nil, err
+	}
+	return &domain.OAuthToken{
+		AccessToken: token.AccessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   token.ExpiresIn,
+		Scope:       strings.Join(granted, " "),
+	}, nil
+}
+
+// containsString reports whether the slice contains the string
+func containsString(slice []string, s string) bool {
+	for _, v := range slice {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
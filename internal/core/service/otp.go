@@ -2,11 +2,9 @@ package service
 
 import (
 	"context"
-	"crypto/rand"
-	"fmt"
-	"io"
-	"strconv"
-	"strings"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
 	"time"
 
 	"github.com/arasan1289/hexagonal-demo/internal/adapters/config"
@@ -16,71 +14,213 @@ import (
 	"github.com/arasan1289/hexagonal-demo/internal/core/util"
 )
 
-// OtpService struct contains a logger and configuration object
+const (
+	otpTTL = time.Minute * 10
+
+	defaultOtpMaxAttempts    = 5
+	defaultOtpResendCooldown = time.Minute
+
+	// otpPrefix prefixes the cache keys of the OTP states, keyed by phone number blind index
+	otpPrefix = "otp:"
+	// otpAttemptsPrefix prefixes the cache keys of the verify attempt counters, keyed by phone number blind index
+	otpAttemptsPrefix = "otp_attempts:"
+)
+
+// OtpService struct contains a logger, configuration object, the cache holding the OTP states and the notifier delivering them
 type OtpService struct {
-	log    *logger.Logger
-	config *config.App
+	log      *logger.Logger
+	config   *config.App
+	cache    port.ICache
+	notifier port.INotificationService
 }
 
 // NewOtpService constructor function
-func NewOtpService(log *logger.Logger, config *config.App) port.IOtpService {
+func NewOtpService(log *logger.Logger, config *config.App, cache port.ICache, notifier port.INotificationService) port.IOtpService {
 	return &OtpService{
-		log:    log,
-		config: config,
+		log:      log,
+		config:   config,
+		cache:    cache,
+		notifier: notifier,
 	}
 }
 
-// GenerateOTP generates a new OTP with the given length
-func (os *OtpService) GenerateOTP(ctx context.Context, length uint) (*domain.OTP, error) {
-	// Create a byte slice of the given length
-	b := make([]byte, length)
-	if _, err := io.ReadFull(rand.Reader, b); err != nil {
+// GenerateOTP generates a new OTP for the phone number, replacing the previously issued one
+func (os *OtpService) GenerateOTP(ctx context.Context, phoneNumber string) (*domain.OTP, error) {
+	key := otpKey(os.config, phoneNumber)
+	state, err := os.getState(ctx, key)
+	if err != nil && !errors.Is(err, domain.ErrDataNotFound) {
 		return nil, err
 	}
+	now := time.Now()
+	if state != nil && now.Before(state.ResendAt) {
+		return nil, domain.ErrOTPResendCooldown
+	}
 
-	// Define a character set for the OTP
-	const charset = "1234567890"
-	charsetLen := len(charset)
-
-	// Convert the random bytes to digits using the character set
-	for i := range b {
-		b[i] = charset[int(b[i])%charsetLen]
+	code, err := util.GenerateNumericCode(int(os.config.OtpLength))
+	if err != nil {
+		return nil, err
 	}
 
 	otp := domain.OTP{
-		Otp: string(b),
+		Otp:       code,
+		ExpiresAt: now.Add(otpTTL),
+		ResendAt:  now.Add(os.resendCooldown()),
 	}
-	// Encrypt the OTP and add the expiry time to the hash
-	expiry := time.Now().Add(time.Minute * 10).UnixMilli()
+	// Only the encrypted OTP is kept on the server
 	otpEnc, err := util.EncryptString(otp.Otp, os.config.OtpSecretKey)
 	if err != nil {
 		return nil, err
 	}
-	otp.OtpHash = fmt.Sprintf("%d.%s", expiry, otpEnc)
+	state = &domain.OTPState{
+		OtpEncrypted: otpEnc,
+		ExpiresAt:    otp.ExpiresAt,
+		ResendAt:     otp.ResendAt,
+	}
+	if err := os.setState(ctx, key, state); err != nil {
+		return nil, err
+	}
+	// The new OTP gets a fresh attempt budget
+	if err := os.cache.Delete(ctx, otpAttemptsKey(os.config, phoneNumber)); err != nil {
+		return nil, err
+	}
 
 	return &otp, nil
 }
 
-// VerifyOTP verifies the given OTP
-func (os *OtpService) VerifyOTP(ctx context.Context, otp *domain.OTP) (bool, error) {
-	// Split the OTP hash into the expiry time and the encrypted OTP
-	exp, otpEnc, _ := strings.Cut(otp.OtpHash, ".")
-	expiry, err := strconv.ParseInt(exp, 10, 64)
+// SendOTP generates a new OTP for the user's phone number and delivers it through the channel in the given locale.
+// The OTP is discarded when it can not be delivered so a new one can be requested right away.
+func (os *OtpService) SendOTP(ctx context.Context, user *domain.User, channel domain.NotificationChannel, locale string) (*domain.OTP, error) {
+	if _, err := notificationRecipient(user, channel); err != nil {
+		return nil, err
+	}
+
+	otp, err := os.GenerateOTP(ctx, user.PhoneNumber)
 	if err != nil {
+		return nil, err
+	}
+
+	data := map[string]any{
+		"app_name":       os.config.Name,
+		"otp":            otp.Otp,
+		"expiry_minutes": int(otpTTL.Minutes()),
+	}
+	if err := notifyUser(ctx, os.notifier, user, channel, domain.TemplateOTP, locale, data); err != nil {
+		if delErr := os.cache.Delete(ctx, otpKey(os.config, user.PhoneNumber)); delErr != nil {
+			os.log.Error().Err(delErr).Msg("Error discarding undelivered OTP")
+		}
+		return nil, err
+	}
+	return otp, nil
+}
+
+// VerifyOTP verifies the given OTP for the phone number, a matching OTP can be used only once.
+// Every attempt is counted atomically before comparing, so parallel guesses can not exceed the attempt limit.
+func (os *OtpService) VerifyOTP(ctx context.Context, phoneNumber, otp string) (bool, error) {
+	key := otpKey(os.config, phoneNumber)
+	state, err := os.getState(ctx, key)
+	if err != nil {
+		if errors.Is(err, domain.ErrDataNotFound) {
+			return false, domain.ErrOTPNotFound
+		}
 		return false, err
 	}
+
 	// Check if the OTP has expired
-	currTime := time.Now().UnixMilli()
-	if currTime >= expiry {
+	if !time.Now().Before(state.ExpiresAt) {
 		return false, domain.ErrOTPExpired
 	}
+	attempts, err := os.cache.Increment(ctx, otpAttemptsKey(os.config, phoneNumber), time.Until(state.ExpiresAt))
+	if err != nil {
+		return false, err
+	}
+	if attempts > int64(os.maxAttempts()) {
+		return false, domain.ErrOTPAttemptsExceeded
+	}
+
 	// Decrypt the OTP and compare it to the given OTP
-	otpDec, err := util.DecryptString(otpEnc, os.config.OtpSecretKey)
+	otpDec, err := util.DecryptString(state.OtpEncrypted, os.config.OtpSecretKey)
 	if err != nil {
 		return false, err
 	}
-	if otpDec != otp.Otp {
+	if subtle.ConstantTimeCompare([]byte(otpDec), []byte(otp)) != 1 {
+		if attempts >= int64(os.maxAttempts()) {
+			return false, domain.ErrOTPAttemptsExceeded
+		}
 		return false, domain.ErrOTPMismatch
 	}
+
+	// Consume the OTP, only the request removing it succeeds when the same OTP is verified concurrently
+	val, err := os.cache.GetDelete(ctx, key)
+	if err != nil {
+		if errors.Is(err, domain.ErrDataNotFound) {
+			return false, domain.ErrOTPNotFound
+		}
+		return false, err
+	}
+	var consumed domain.OTPState
+	if err := json.Unmarshal(val, &consumed); err != nil {
+		return false, err
+	}
+	if consumed.OtpEncrypted != state.OtpEncrypted {
+		// A new OTP was issued in the meantime, it was consumed by mistake and has to be requested again
+		return false, domain.ErrOTPNotFound
+	}
+	if err := os.cache.Delete(ctx, otpAttemptsKey(os.config, phoneNumber)); err != nil {
+		os.log.Error().Err(err).Msg("Error deleting OTP attempts")
+	}
 	return true, nil
 }
+
+// getState loads the OTP state stored under the key
+func (os *OtpService) getState(ctx context.Context, key string) (*domain.OTPState, error) {
+	val, err := os.cache.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	var state domain.OTPState
+	if err := json.Unmarshal(val, &state); err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+// setState stores the OTP state until both the OTP and the resend cooldown have expired
+func (os *OtpService) setState(ctx context.Context, key string, state *domain.OTPState) error {
+	val, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	expiry := state.ExpiresAt
+	if state.ResendAt.After(expiry) {
+		expiry = state.ResendAt
+	}
+	ttl := time.Until(expiry)
+	if ttl <= 0 {
+		return os.cache.Delete(ctx, key)
+	}
+	return os.cache.Set(ctx, key, val, ttl)
+}
+
+func (os *OtpService) maxAttempts() uint {
+	if os.config.OtpMaxAttempts == 0 {
+		return defaultOtpMaxAttempts
+	}
+	return os.config.OtpMaxAttempts
+}
+
+func (os *OtpService) resendCooldown() time.Duration {
+	if os.config.OtpResendCooldown == 0 {
+		return defaultOtpResendCooldown
+	}
+	return time.Duration(os.config.OtpResendCooldown) * time.Second
+}
+
+// otpKey builds the cache key of the OTP state of the phone number
+func otpKey(conf *config.App, phoneNumber string) string {
+	return otpPrefix + blindIndex(conf, phoneNumber)
+}
+
+// otpAttemptsKey builds the cache key of the verify attempt counter of the phone number
+func otpAttemptsKey(conf *config.App, phoneNumber string) string {
+	return otpAttemptsPrefix + blindIndex(conf, phoneNumber)
+}
@@ -0,0 +1,146 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/arasan1289/hexagonal-demo/internal/adapters/config"
+	"github.com/arasan1289/hexagonal-demo/internal/core/domain"
+)
+
+const testPhoneNumber = "9876543210"
+
+func newTestOtpService() *OtpService {
+	return &OtpService{
+		log: testLogger,
+		config: &config.App{
+			OtpSecretKey:   "0123456789abcdef0123456789abcdef",
+			OtpLength:      6,
+			OtpMaxAttempts: 3,
+			BlindIndexKey:  "blind-index-key",
+		},
+		cache: newFakeCache(),
+	}
+}
+
+// wrongOTP returns a code of the same length which is not the OTP
+func wrongOTP(otp string) string {
+	if otp == "000000" {
+		return "111111"
+	}
+	return "000000"
+}
+
+func TestVerifyOTP(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name    string
+		wrong   int
+		wantErr error
+	}{
+		{"correct OTP", 0, nil},
+		{"correct OTP after a wrong one", 1, nil},
+		{"correct OTP after the last allowed wrong one", 2, nil},
+		{"attempts exhausted", 3, domain.ErrOTPAttemptsExceeded},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os := newTestOtpService()
+			otp, err := os.GenerateOTP(ctx, testPhoneNumber)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := 0; i < tt.wrong; i++ {
+				want := domain.ErrOTPMismatch
+				if i == int(os.maxAttempts())-1 {
+					want = domain.ErrOTPAttemptsExceeded
+				}
+				if _, err := os.VerifyOTP(ctx, testPhoneNumber, wrongOTP(otp.Otp)); !errors.Is(err, want) {
+					t.Fatalf("VerifyOTP() wrong attempt %d error = %v, want %v", i+1, err, want)
+				}
+			}
+
+			ok, err := os.VerifyOTP(ctx, testPhoneNumber, otp.Otp)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("VerifyOTP() error = %v, want %v", err, tt.wantErr)
+			}
+			if ok != (tt.wantErr == nil) {
+				t.Fatalf("VerifyOTP() = %v, want %v", ok, tt.wantErr == nil)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			// An OTP is used once
+			if _, err := os.VerifyOTP(ctx, testPhoneNumber, otp.Otp); !errors.Is(err, domain.ErrOTPNotFound) {
+				t.Errorf("VerifyOTP() of a used OTP error = %v, want %v", err, domain.ErrOTPNotFound)
+			}
+		})
+	}
+}
+
+func TestVerifyOTPConcurrent(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("wrong guesses", func(t *testing.T) {
+		os := newTestOtpService()
+		otp, err := os.GenerateOTP(ctx, testPhoneNumber)
+		if err != nil {
+			t.Fatal(err)
+		}
+		const guesses = 20
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		var mismatches int
+		for i := 0; i < guesses; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := os.VerifyOTP(ctx, testPhoneNumber, wrongOTP(otp.Otp))
+				if errors.Is(err, domain.ErrOTPMismatch) {
+					mu.Lock()
+					mismatches++
+					mu.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+
+		if max := int(os.maxAttempts()); mismatches != max-1 {
+			t.Errorf("%d parallel guesses were compared, want %d", mismatches, max-1)
+		}
+		if _, err := os.VerifyOTP(ctx, testPhoneNumber, otp.Otp); !errors.Is(err, domain.ErrOTPAttemptsExceeded) {
+			t.Errorf("VerifyOTP() after the parallel guesses error = %v, want %v", err, domain.ErrOTPAttemptsExceeded)
+		}
+	})
+
+	t.Run("same OTP", func(t *testing.T) {
+		os := newTestOtpService()
+		os.config.OtpMaxAttempts = 20
+		otp, err := os.GenerateOTP(ctx, testPhoneNumber)
+		if err != nil {
+			t.Fatal(err)
+		}
+		const requests = 10
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		var verified int
+		for i := 0; i < requests; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if ok, _ := os.VerifyOTP(ctx, testPhoneNumber, otp.Otp); ok {
+					mu.Lock()
+					verified++
+					mu.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+
+		if verified != 1 {
+			t.Errorf("the same OTP was verified %d times, want 1", verified)
+		}
+	})
+}
@@ -0,0 +1,215 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/arasan1289/hexagonal-demo/internal/adapters/config"
+	"github.com/arasan1289/hexagonal-demo/internal/adapters/logger"
+	"github.com/arasan1289/hexagonal-demo/internal/core/domain"
+	"github.com/arasan1289/hexagonal-demo/internal/core/port"
+	"github.com/arasan1289/hexagonal-demo/internal/core/util"
+)
+
+const (
+	defaultOutboxPollInterval = time.Second * 5
+	defaultOutboxBatchSize    = 50
+	defaultOutboxMaxAttempts  = 8
+
+	outboxBaseBackoff = time.Second * 10
+	outboxMaxBackoff  = time.Hour
+
+	// outboxLease is how long claimed messages are left to their dispatcher before another one claims them again
+	outboxLease = time.Minute * 5
+	// outboxLastErrorSize is the size of the last_error column
+	outboxLastErrorSize = 1000
+)
+
+// outboxHandler publishes a single outbox message
+type outboxHandler func(ctx context.Context, msg *domain.OutboxMessage) error
+
+// OutboxService dispatches the outbox messages to the handler of their topic
+type OutboxService struct {
+	repo      port.IOutboxRepository
+	userRepo  port.IUserRepository
+	notifier  port.INotificationService
+	exportSvc port.IDataExportService
+	log       *logger.Logger
+	config    *config.App
+	handlers  map[string]outboxHandler
+}
+
+// NewOutboxService constructor function
+func NewOutboxService(repo port.IOutboxRepository, userRepo port.IUserRepository, notifier port.INotificationService, exportSvc port.IDataExportService, log *logger.Logger, config *config.App) port.IOutboxService {
+	os := &OutboxService{
+		repo:      repo,
+		userRepo:  userRepo,
+		notifier:  notifier,
+		exportSvc: exportSvc,
+		log:       log,
+		config:    config,
+	}
+	os.handlers = map[string]outboxHandler{
+		domain.OutboxTopicNotification: os.publishNotification,
+		domain.OutboxTopicDataExport:   os.publishDataExport,
+	}
+	return os
+}
+
+// Run polls the outbox until the context is cancelled
+func (os *OutboxService) Run(ctx context.Context) {
+	interval := defaultOutboxPollInterval
+	if os.config.OutboxPollInterval != 0 {
+		interval = time.Duration(os.config.OutboxPollInterval) * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		// Keep draining while full batches are returned
+		for {
+			n, err := os.dispatch(ctx)
+			if err != nil {
+				os.log.Error().Err(err).Msg("Error dispatching outbox messages")
+				break
+			}
+			if n < os.batchSize() {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// dispatch claims one batch of due messages, publishes them and saves the outcome of every message
+func (os *OutboxService) dispatch(ctx context.Context) (int, error) {
+	msgs, err := os.repo.ClaimPending(ctx, util.GenerateULID(), time.Now().Add(outboxLease), os.batchSize())
+	if err != nil {
+		return 0, err
+	}
+
+	for i := range msgs {
+		msg := &msgs[i]
+		if msg.Attempts > os.maxAttempts() {
+			// Every claim counts as an attempt, also when the lease expired because publishing hung or crashed
+			msg.Status = domain.OutboxDead
+			os.log.Error().Str("outbox_id", msg.ID).Str("topic", msg.Topic).Msg("Outbox message moved to dead letter after its leases expired")
+		} else {
+			os.setOutcome(msg, os.publish(ctx, msg))
+		}
+		if err := os.repo.CompleteMessage(ctx, msg); err != nil {
+			if errors.Is(err, domain.ErrDataNotFound) {
+				os.log.Warn().Str("outbox_id", msg.ID).Msg("Outbox message lease expired while publishing")
+				continue
+			}
+			return len(msgs), err
+		}
+	}
+	return len(msgs), nil
+}
+
+// setOutcome sets the delivery state after publishing, failed messages are retried with backoff until they are dead
+func (os *OutboxService) setOutcome(msg *domain.OutboxMessage, err error) {
+	if err == nil {
+		msg.Status = domain.OutboxSent
+		msg.LastError = ""
+		return
+	}
+
+	// The attempt was already counted when the message was claimed
+	msg.LastError = truncateString(err.Error(), outboxLastErrorSize)
+	if msg.Attempts >= os.maxAttempts() {
+		msg.Status = domain.OutboxDead
+		os.log.Error().Err(err).Str("outbox_id", msg.ID).Str("topic", msg.Topic).Msg("Outbox message moved to dead letter")
+		return
+	}
+	msg.Status = domain.OutboxPending
+	msg.NextAttemptAt = time.Now().Add(outboxBackoff(msg.Attempts))
+	os.log.Warn().Err(err).Str("outbox_id", msg.ID).Uint("attempts", msg.Attempts).Msg("Outbox message publish failed, retrying")
+}
+
+func (os *OutboxService) publish(ctx context.Context, msg *domain.OutboxMessage) error {
+	handler, ok := os.handlers[msg.Topic]
+	if !ok {
+		return fmt.Errorf("no handler for outbox topic %q", msg.Topic)
+	}
+	return handler(ctx, msg)
+}
+
+// publishNotification delivers a NotificationEvent to its user
+func (os *OutboxService) publishNotification(ctx context.Context, msg *domain.OutboxMessage) error {
+	var event domain.NotificationEvent
+	if err := json.Unmarshal([]byte(msg.Payload), &event); err != nil {
+		return err
+	}
+	user, err := os.userRepo.GetUser(ctx, event.UserID)
+	if err != nil {
+		return err
+	}
+	return notifyUser(ctx, os.notifier, user, event.Channel, event.Template, event.Locale, event.Data)
+}
+
+// publishDataExport builds a DataExportEvent and sends it to its user
+func (os *OutboxService) publishDataExport(ctx context.Context, msg *domain.OutboxMessage) error {
+	var event domain.DataExportEvent
+	if err := json.Unmarshal([]byte(msg.Payload), &event); err != nil {
+		return err
+	}
+	return os.exportSvc.BuildExport(ctx, &event)
+}
+
+func (os *OutboxService) batchSize() int {
+	if os.config.OutboxBatchSize == 0 {
+		return defaultOutboxBatchSize
+	}
+	return int(os.config.OutboxBatchSize)
+}
+
+func (os *OutboxService) maxAttempts() uint {
+	if os.config.OutboxMaxAttempts == 0 {
+		return defaultOutboxMaxAttempts
+	}
+	return os.config.OutboxMaxAttempts
+}
+
+// outboxBackoff doubles the delay with every failed attempt up to outboxMaxBackoff
+func outboxBackoff(attempts uint) time.Duration {
+	backoff := float64(outboxBaseBackoff) * math.Pow(2, float64(attempts-1))
+	if backoff > float64(outboxMaxBackoff) {
+		return outboxMaxBackoff
+	}
+	return time.Duration(backoff)
+}
+
+// truncateString cuts the string to at most n characters
+func truncateString(str string, n int) string {
+	runes := []rune(str)
+	if len(runes) <= n {
+		return str
+	}
+	return string(runes[:n])
+}
+
+// newOutboxMessage creates a pending outbox message for the topic with the JSON encoded payload
+func newOutboxMessage(topic string, payload any) (*domain.OutboxMessage, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	return &domain.OutboxMessage{
+		BaseModel:     domain.BaseModel{ID: util.GenerateULID()},
+		Topic:         topic,
+		Payload:       string(data),
+		Status:        domain.OutboxPending,
+		NextAttemptAt: time.Now(),
+	}, nil
+}
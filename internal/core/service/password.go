@@ -0,0 +1,169 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/arasan1289/hexagonal-demo/internal/adapters/config"
+	"github.com/arasan1289/hexagonal-demo/internal/adapters/logger"
+	"github.com/arasan1289/hexagonal-demo/internal/core/domain"
+	"github.com/arasan1289/hexagonal-demo/internal/core/port"
+	"github.com/arasan1289/hexagonal-demo/internal/core/util"
+)
+
+const (
+	passwordResetTTL = time.Minute * 15
+
+	// passwordResetPrefix prefixes the cache keys of reset tokens, keyed by token hash
+	passwordResetPrefix = "password_reset:"
+	// passwordResetUserPrefix prefixes the cache keys holding the hash of the latest reset token of a user
+	passwordResetUserPrefix = "password_reset_user:"
+)
+
+// PasswordService struct represents the password management service with its dependencies
+type PasswordService struct {
+	repo     port.IUserRepository
+	authSvc  port.IAuthService
+	cache    port.ICache
+	notifier port.INotificationService
+	log      *logger.Logger
+	config   *config.App
+	policy   *config.PasswordPolicy
+}
+
+// NewPasswordService constructor function
+func NewPasswordService(repo port.IUserRepository, authSvc port.IAuthService, cache port.ICache, notifier port.INotificationService, log *logger.Logger, config *config.App, policy *config.PasswordPolicy) port.IPasswordService {
+	return &PasswordService{
+		repo:     repo,
+		authSvc:  authSvc,
+		cache:    cache,
+		notifier: notifier,
+		log:      log,
+		config:   config,
+		policy:   policy,
+	}
+}
+
+// ChangePassword sets the user's password, the current password must match when one is already set
+func (ps *PasswordService) ChangePassword(ctx context.Context, userID, currentPassword, newPassword string) error {
+	user, err := ps.repo.GetUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if user.Password != nil {
+		match, err := comparePasswordAndHash(currentPassword, *user.Password)
+		if err != nil {
+			return err
+		}
+		if !match {
+			return domain.ErrInvalidCredentials
+		}
+	}
+
+	if err := validatePassword(newPassword, ps.policy); err != nil {
+		return err
+	}
+	passwordHash, err := generatePasswordHash(newPassword)
+	if err != nil {
+		return err
+	}
+	user.Password = &passwordHash
+	_, err = ps.repo.UpdateUser(ctx, user, "password")
+	return err
+}
+
+// ForgotPassword sends a single-use reset token to the user, replacing any token sent before. Tokens are emailed
+// only to verified emails. Unknown identifiers are ignored so the response does not reveal which accounts exist.
+func (ps *PasswordService) ForgotPassword(ctx context.Context, identifier string, channel domain.NotificationChannel, locale string) error {
+	user, err := ps.repo.GetUserByPhoneNumberOrEmail(ctx, blindIndexes(ps.config, identifier)...)
+	if err != nil {
+		if errors.Is(err, domain.ErrDataNotFound) {
+			ps.log.Info().Msg("Password reset requested for unknown user")
+			return nil
+		}
+		return err
+	}
+
+	if channel == "" {
+		channel = domain.ChannelSMS
+		if user.Email != nil && user.IsEmailVerified {
+			channel = domain.ChannelEmail
+		}
+	}
+	// Users without an address on the channel are ignored like unknown users
+	if _, err := notificationRecipient(user, channel); err != nil {
+		if errors.Is(err, domain.ErrEmailNotSet) {
+			ps.log.Info().Str("user_id", user.ID).Msg("Password reset requested for user without email")
+			return nil
+		}
+		return err
+	}
+	// Reset tokens are only sent to an email the user proved to own
+	if channel == domain.ChannelEmail && !user.IsEmailVerified {
+		ps.log.Info().Str("user_id", user.ID).Msg("Password reset requested for user with unverified email")
+		return nil
+	}
+
+	token, err := util.GenerateToken(32)
+	if err != nil {
+		return err
+	}
+	tokenHash := util.HashString(token)
+
+	// Only the latest token of a user stays valid
+	userKey := passwordResetUserPrefix + user.ID
+	if prev, err := ps.cache.Get(ctx, userKey); err == nil {
+		if err := ps.cache.Delete(ctx, passwordResetPrefix+string(prev)); err != nil {
+			return err
+		}
+	} else if !errors.Is(err, domain.ErrDataNotFound) {
+		return err
+	}
+	if err := ps.cache.Set(ctx, passwordResetPrefix+tokenHash, []byte(user.ID), passwordResetTTL); err != nil {
+		return err
+	}
+	if err := ps.cache.Set(ctx, userKey, []byte(tokenHash), passwordResetTTL); err != nil {
+		return err
+	}
+
+	data := map[string]any{
+		"app_name":       ps.config.Name,
+		"first_name":     user.FirstName,
+		"code":           token,
+		"expiry_minutes": int(passwordResetTTL.Minutes()),
+	}
+	return notifyUser(ctx, ps.notifier, user, channel, domain.TemplatePasswordReset, locale, data)
+}
+
+// ResetPassword consumes the reset token, stores the new password hash and revokes every token of the user
+func (ps *PasswordService) ResetPassword(ctx context.Context, token, password string) error {
+	if err := validatePassword(password, ps.policy); err != nil {
+		return err
+	}
+
+	// Consume the token before changing anything, only one of concurrent requests with the same token gets it
+	userID, err := ps.cache.GetDelete(ctx, passwordResetPrefix+util.HashString(token))
+	if err != nil {
+		if errors.Is(err, domain.ErrDataNotFound) {
+			return domain.ErrInvalidResetToken
+		}
+		return err
+	}
+	if err := ps.cache.Delete(ctx, passwordResetUserPrefix+string(userID)); err != nil {
+		return err
+	}
+
+	passwordHash, err := generatePasswordHash(password)
+	if err != nil {
+		return err
+	}
+	user := &domain.User{Password: &passwordHash}
+	user.ID = string(userID)
+	if _, err := ps.repo.UpdateUser(ctx, user, "password"); err != nil {
+		return err
+	}
+
+	return ps.authSvc.RevokeAllJWT(ctx, user.ID)
+}
@@ -0,0 +1,60 @@
+package service
+
+import (
+	_ "embed"
+	"strings"
+	"unicode"
+
+	"github.com/arasan1289/hexagonal-demo/internal/adapters/config"
+	"github.com/arasan1289/hexagonal-demo/internal/core/domain"
+)
+
+const defaultPasswordMinLength = 8
+
+//go:embed common_passwords.txt
+var commonPasswordList string
+
+// commonPasswords is the set of bundled commonly used passwords, stored lower cased
+var commonPasswords = func() map[string]struct{} {
+	set := make(map[string]struct{})
+	for _, p := range strings.Fields(commonPasswordList) {
+		set[strings.ToLower(p)] = struct{}{}
+	}
+	return set
+}()
+
+// validatePassword checks the password against the policy and the list of common passwords
+func validatePassword(password string, policy *config.PasswordPolicy) error {
+	minLength := uint(defaultPasswordMinLength)
+	if policy.MinLength != 0 {
+		minLength = policy.MinLength
+	}
+	if uint(len([]rune(password))) < minLength {
+		return domain.ErrPasswordTooShort
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			hasSymbol = true
+		}
+	}
+	if (policy.RequireUpper && !hasUpper) ||
+		(policy.RequireLower && !hasLower) ||
+		(policy.RequireDigit && !hasDigit) ||
+		(policy.RequireSymbol && !hasSymbol) {
+		return domain.ErrPasswordTooWeak
+	}
+
+	if _, ok := commonPasswords[strings.ToLower(password)]; ok {
+		return domain.ErrPasswordTooCommon
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/arasan1289/hexagonal-demo/internal/core/domain"
+	"github.com/arasan1289/hexagonal-demo/internal/core/util"
+)
+
+const (
+	phoneNumberVerificationTTL         = time.Minute * 10
+	phoneNumberVerificationCodeLength  = 6
+	phoneNumberVerificationMaxAttempts = 5
+
+	// phoneNumberVerificationPrefix prefixes the cache keys of pending phone number changes, keyed by user ID
+	phoneNumberVerificationPrefix = "phone_number_verification:"
+	// phoneNumberAttemptsPrefix prefixes the cache keys of the verify attempt counters, keyed by user ID
+	phoneNumberAttemptsPrefix = "phone_number_attempts:"
+)
+
+// ChangePhoneNumber sends an OTP to the new phone number, the phone number of the user is only
+// replaced once the OTP is verified. Requesting again replaces the pending change.
+func (us *UserService) ChangePhoneNumber(ctx context.Context, userID, phoneNumber, locale string) error {
+	user, err := us.repo.GetUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := us.checkPhoneNumberAvailable(ctx, userID, phoneNumber); err != nil {
+		return err
+	}
+
+	code, err := util.GenerateNumericCode(phoneNumberVerificationCodeLength)
+	if err != nil {
+		return err
+	}
+	phoneNumberEnc, err := util.EncryptString(phoneNumber, us.config.SecretKey)
+	if err != nil {
+		return err
+	}
+	state := domain.PhoneNumberVerification{
+		PhoneNumberEncrypted: phoneNumberEnc,
+		CodeHash:             util.HashString(code),
+		ExpiresAt:            time.Now().Add(phoneNumberVerificationTTL),
+	}
+	val, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	key := phoneNumberVerificationPrefix + userID
+	if err := us.cache.Set(ctx, key, val, phoneNumberVerificationTTL); err != nil {
+		return err
+	}
+	// The new code gets a fresh attempt budget
+	if err := us.cache.Delete(ctx, phoneNumberAttemptsPrefix+userID); err != nil {
+		return err
+	}
+
+	if locale == "" {
+		locale = user.Locale
+	}
+	data := map[string]any{
+		"app_name":       us.config.Name,
+		"otp":            code,
+		"expiry_minutes": int(phoneNumberVerificationTTL.Minutes()),
+	}
+	if err := sendNotification(ctx, us.notifier, domain.ChannelSMS, phoneNumber, domain.TemplateOTP, locale, data); err != nil {
+		if delErr := us.cache.Delete(ctx, key); delErr != nil {
+			us.log.Error().Err(delErr).Msg("Error discarding undelivered phone number verification")
+		}
+		return err
+	}
+	return nil
+}
+
+// VerifyPhoneNumber checks the OTP of the pending phone number change, then stores the phone number as the user's
+// verified phone number. Attempts are counted atomically and the pending change is consumed atomically, so
+// parallel guesses can neither exceed the attempt limit nor apply the change twice.
+func (us *UserService) VerifyPhoneNumber(ctx context.Context, userID, code string) (*domain.User, error) {
+	key := phoneNumberVerificationPrefix + userID
+	val, err := us.cache.Get(ctx, key)
+	if err != nil {
+		if errors.Is(err, domain.ErrDataNotFound) {
+			return nil, domain.ErrInvalidVerificationCode
+		}
+		return nil, err
+	}
+	var state domain.PhoneNumberVerification
+	if err := json.Unmarshal(val, &state); err != nil {
+		return nil, err
+	}
+	if !time.Now().Before(state.ExpiresAt) {
+		return nil, domain.ErrInvalidVerificationCode
+	}
+	attempts, err := us.cache.Increment(ctx, phoneNumberAttemptsPrefix+userID, time.Until(state.ExpiresAt))
+	if err != nil {
+		return nil, err
+	}
+	if attempts > phoneNumberVerificationMaxAttempts {
+		return nil, domain.ErrVerificationAttemptsExceeded
+	}
+	if subtle.ConstantTimeCompare([]byte(state.CodeHash), []byte(util.HashString(code))) != 1 {
+		if attempts >= phoneNumberVerificationMaxAttempts {
+			return nil, domain.ErrVerificationAttemptsExceeded
+		}
+		return nil, domain.ErrInvalidVerificationCode
+	}
+
+	consumed, err := us.cache.GetDelete(ctx, key)
+	if err != nil {
+		if errors.Is(err, domain.ErrDataNotFound) {
+			return nil, domain.ErrInvalidVerificationCode
+		}
+		return nil, err
+	}
+	if string(consumed) != string(val) {
+		// Another change was requested in the meantime, it was consumed by mistake and has to be requested again
+		return nil, domain.ErrInvalidVerificationCode
+	}
+	if err := us.cache.Delete(ctx, phoneNumberAttemptsPrefix+userID); err != nil {
+		us.log.Error().Err(err).Msg("Error deleting phone number verification attempts")
+	}
+
+	phoneNumber, err := util.DecryptField(state.PhoneNumberEncrypted, nil, us.config.SecretKeys()...)
+	if err != nil {
+		return nil, err
+	}
+	// The phone number may have been taken while the change was pending
+	if err := us.checkPhoneNumberAvailable(ctx, userID, phoneNumber); err != nil {
+		return nil, err
+	}
+
+	user, err := us.repo.GetUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	phoneNumberEnc, err := encryptUserData(ctx, us.repo, us.keys, user, phoneNumber)
+	if err != nil {
+		return nil, err
+	}
+	user.PhoneNumber = phoneNumber
+	user.PhoneNumberEncrypted = phoneNumberEnc
+	user.PhoneNumberHash = blindIndex(us.config, phoneNumber)
+	user.IsPhoneNumberVerified = true
+	return us.repo.UpdateUser(ctx, user, "phone_number_encrypted", "phone_number_hash", "is_phone_number_verified")
+}
+
+// checkPhoneNumberAvailable returns ErrConflictingData when another user has the phone number
+func (us *UserService) checkPhoneNumberAvailable(ctx context.Context, userID, phoneNumber string) error {
+	other, err := us.repo.GetUserByPhoneNumberOrEmail(ctx, blindIndexes(us.config, phoneNumber)...)
+	if err != nil {
+		if errors.Is(err, domain.ErrDataNotFound) {
+			return nil
+		}
+		return err
+	}
+	if other.ID != userID {
+		return domain.ErrConflictingData
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/arasan1289/hexagonal-demo/internal/adapters/config"
+	"github.com/arasan1289/hexagonal-demo/internal/adapters/logger"
+	"github.com/arasan1289/hexagonal-demo/internal/core/domain"
+	"github.com/arasan1289/hexagonal-demo/internal/core/port"
+	"github.com/arasan1289/hexagonal-demo/internal/core/util"
+)
+
+const defaultReencryptionBatchSize = 500
+
+// ReencryptionService moves the personal data of users to the current keys. Data still encrypted with a
+// secret key is encrypted with the user's data key, data keys wrapped by an old master key are wrapped
+// by the current one and phone number and email hashes are recomputed with the current blind index key.
+type ReencryptionService struct {
+	repo   port.IUserRepository
+	keys   port.IKeyManager
+	log    *logger.Logger
+	config *config.App
+}
+
+// NewReencryptionService constructor function
+func NewReencryptionService(repo port.IUserRepository, keys port.IKeyManager, log *logger.Logger, config *config.App) port.IReencryptionService {
+	return &ReencryptionService{
+		repo:   repo,
+		keys:   keys,
+		log:    log,
+		config: config,
+	}
+}
+
+// Reencrypt walks the users in ULID order and logs the progress after every batch. Users already on the
+// current keys are skipped, so running it again after an interruption only re-encrypts the remaining users.
+func (rs *ReencryptionService) Reencrypt(ctx context.Context, after string, batchSize int) (*domain.ReencryptionProgress, error) {
+	if batchSize <= 0 {
+		batchSize = defaultReencryptionBatchSize
+	}
+	progress := &domain.ReencryptionProgress{LastID: after}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return progress, err
+		}
+		users, err := rs.repo.ListUsersAfter(ctx, progress.LastID, batchSize)
+		if err != nil {
+			return progress, err
+		}
+
+		for i := range users {
+			outdated, saved, err := rs.reencryptUser(ctx, &users[i])
+			if err != nil {
+				return progress, fmt.Errorf("user %s: %w", users[i].ID, err)
+			}
+			switch {
+			case !outdated:
+			case saved:
+				progress.Reencrypted++
+			default:
+				progress.Conflicts++
+				rs.log.Warn().Str("user_id", users[i].ID).Msg("User changed while re-encrypting, left for the next run")
+			}
+			progress.Scanned++
+			progress.LastID = users[i].ID
+		}
+
+		rs.log.Info().
+			Int("scanned", progress.Scanned).
+			Int("reencrypted", progress.Reencrypted).
+			Int("conflicts", progress.Conflicts).
+			Str("last_id", progress.LastID).
+			Msg("Re-encryption progress")
+		if len(users) < batchSize {
+			return progress, nil
+		}
+	}
+}
+
+// reencryptUser re-encrypts the user when it is on old keys or blind indexes and reports whether the new ciphertexts were saved
+func (rs *ReencryptionService) reencryptUser(ctx context.Context, user *domain.User) (outdated, saved bool, err error) {
+	old := domain.UserCiphertexts{
+		DataKey:              user.DataKey,
+		PhoneNumberEncrypted: user.PhoneNumberEncrypted,
+		PhoneNumberHash:      user.PhoneNumberHash,
+		EmailEncrypted:       user.EmailEncrypted,
+		EmailHash:            user.EmailHash,
+		TOTPSecretEncrypted:  user.TOTPSecretEncrypted,
+	}
+	outdatedPhoneNumber := old.PhoneNumberEncrypted != "" && !util.IsDataKeyEncrypted(old.PhoneNumberEncrypted)
+	outdatedEmail := old.EmailEncrypted != nil && !util.IsDataKeyEncrypted(*old.EmailEncrypted)
+	outdatedTOTPSecret := old.TOTPSecretEncrypted != nil && !util.IsDataKeyEncrypted(*old.TOTPSecretEncrypted)
+	outdatedDataKey := old.DataKey != nil && !strings.HasPrefix(*old.DataKey, rs.keys.CurrentKeyPrefix())
+	outdatedCiphertexts := outdatedPhoneNumber || outdatedEmail || outdatedTOTPSecret || outdatedDataKey
+	// Erased users have neither a phone number nor an email left to hash
+	phoneNumberHash := blindIndex(rs.config, user.PhoneNumber)
+	outdatedPhoneNumberHash := user.PhoneNumber != "" && old.PhoneNumberHash != phoneNumberHash
+	var emailHash string
+	if user.Email != nil {
+		emailHash = blindIndex(rs.config, *user.Email)
+	}
+	outdatedEmailHash := user.Email != nil && (old.EmailHash == nil || *old.EmailHash != emailHash)
+	if !outdatedCiphertexts && !outdatedPhoneNumberHash && !outdatedEmailHash {
+		return false, false, nil
+	}
+
+	updated := old
+	if outdatedPhoneNumberHash {
+		updated.PhoneNumberHash = phoneNumberHash
+	}
+	if outdatedEmailHash {
+		updated.EmailHash = &emailHash
+	}
+	if !outdatedCiphertexts {
+		saved, err = rs.repo.SwapUserCiphertexts(ctx, user.ID, &old, &updated)
+		return true, saved, err
+	}
+
+	// The TOTP secret is not decrypted when reading users
+	var totpSecret string
+	if outdatedTOTPSecret {
+		if totpSecret, err = decryptUserData(ctx, rs.keys, user, *old.TOTPSecretEncrypted, rs.config.SecretKeys()); err != nil {
+			return true, false, err
+		}
+	}
+
+	dataKey, err := userDataKey(ctx, rs.repo, rs.keys, user)
+	if err != nil {
+		return true, false, err
+	}
+	// A data key generated for the user was stored right away
+	old.DataKey = user.DataKey
+	if outdatedDataKey {
+		wrapped, err := rs.keys.WrapDataKey(ctx, dataKey)
+		if err != nil {
+			return true, false, err
+		}
+		updated.DataKey = &wrapped
+	} else {
+		updated.DataKey = user.DataKey
+	}
+	if outdatedPhoneNumber {
+		if updated.PhoneNumberEncrypted, err = util.EncryptWithDataKey(user.PhoneNumber, dataKey); err != nil {
+			return true, false, err
+		}
+	}
+	if outdatedEmail {
+		emailEnc, err := util.EncryptWithDataKey(*user.Email, dataKey)
+		if err != nil {
+			return true, false, err
+		}
+		updated.EmailEncrypted = &emailEnc
+	}
+	if outdatedTOTPSecret {
+		secretEnc, err := util.EncryptWithDataKey(totpSecret, dataKey)
+		if err != nil {
+			return true, false, err
+		}
+		updated.TOTPSecretEncrypted = &secretEnc
+	}
+
+	saved, err = rs.repo.SwapUserCiphertexts(ctx, user.ID, &old, &updated)
+	return true, saved, err
+}
@@ -0,0 +1,250 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/arasan1289/hexagonal-demo/internal/adapters/config"
+	"github.com/arasan1289/hexagonal-demo/internal/core/domain"
+	"github.com/arasan1289/hexagonal-demo/internal/core/util"
+)
+
+const (
+	testSecretKey    = "0123456789abcdef0123456789abcdef"
+	testOldSecretKey = "fedcba9876543210fedcba9876543210"
+)
+
+// fakeKeyManager wraps data keys with versioned master keys like the local KMS
+type fakeKeyManager struct {
+	current    uint
+	masterKeys map[uint]string
+}
+
+func (km *fakeKeyManager) GenerateDataKey(ctx context.Context) ([]byte, string, error) {
+	dataKey := make([]byte, 32)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, "", err
+	}
+	wrapped, err := km.WrapDataKey(ctx, dataKey)
+	return dataKey, wrapped, err
+}
+
+func (km *fakeKeyManager) UnwrapDataKey(ctx context.Context, wrapped string) ([]byte, error) {
+	var version uint
+	if _, err := fmt.Sscanf(wrapped, "v%d:", &version); err != nil {
+		return nil, err
+	}
+	dataKey, err := util.DecryptString(strings.TrimPrefix(wrapped, fmt.Sprintf("v%d:", version)), km.masterKeys[version])
+	return []byte(dataKey), err
+}
+
+func (km *fakeKeyManager) WrapDataKey(ctx context.Context, dataKey []byte) (string, error) {
+	wrapped, err := util.EncryptString(string(dataKey), km.masterKeys[km.current])
+	return km.CurrentKeyPrefix() + wrapped, err
+}
+
+func (km *fakeKeyManager) CurrentKeyPrefix() string {
+	return fmt.Sprintf("v%d:", km.current)
+}
+
+func ciphertextsOf(user *domain.User) domain.UserCiphertexts {
+	return domain.UserCiphertexts{
+		DataKey:              user.DataKey,
+		PhoneNumberEncrypted: user.PhoneNumberEncrypted,
+		PhoneNumberHash:      user.PhoneNumberHash,
+		EmailEncrypted:       user.EmailEncrypted,
+		EmailHash:            user.EmailHash,
+		TOTPSecretEncrypted:  user.TOTPSecretEncrypted,
+	}
+}
+
+func TestReencryptUser(t *testing.T) {
+	ctx := context.Background()
+	conf := &config.App{
+		SecretKey:     testSecretKey,
+		OldSecretKeys: []string{testOldSecretKey},
+		BlindIndexKey: "blind-index-key",
+	}
+	keys := &fakeKeyManager{
+		current: 2,
+		masterKeys: map[uint]string{
+			1: "11111111111111111111111111111111",
+			2: "22222222222222222222222222222222",
+		},
+	}
+	oldKeys := &fakeKeyManager{current: 1, masterKeys: keys.masterKeys}
+
+	const phoneNumber = "9876543210"
+	email := "example@example.com"
+	secret := "JBSWY3DPEHPK3PXP"
+
+	mustEncrypt := func(str, secret string) string {
+		encStr, err := util.EncryptString(str, secret)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return encStr
+	}
+	// newUser builds a user as read from the repository, with decrypted phone number and email
+	newUser := func(id string, km *fakeKeyManager, encrypt func(user *domain.User, str string) string) *domain.User {
+		user := &domain.User{PhoneNumber: phoneNumber, Email: &email}
+		user.ID = id
+		if km != nil {
+			_, wrapped, err := km.GenerateDataKey(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			user.DataKey = &wrapped
+		}
+		emailEnc := encrypt(user, email)
+		secretEnc := encrypt(user, secret)
+		user.PhoneNumberEncrypted = encrypt(user, phoneNumber)
+		user.EmailEncrypted = &emailEnc
+		user.TOTPSecretEncrypted = &secretEnc
+		return user
+	}
+	withSecretKey := func(key string) func(*domain.User, string) string {
+		return func(_ *domain.User, str string) string {
+			return mustEncrypt(str, key)
+		}
+	}
+	withDataKey := func(km *fakeKeyManager) func(*domain.User, string) string {
+		return func(user *domain.User, str string) string {
+			dataKey, err := km.UnwrapDataKey(ctx, *user.DataKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			encStr, err := util.EncryptWithDataKey(str, dataKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return encStr
+		}
+	}
+	hashed := func(user *domain.User, phoneNumberHash, emailHash string) *domain.User {
+		user.PhoneNumberHash = phoneNumberHash
+		user.EmailHash = &emailHash
+		return user
+	}
+
+	tests := []struct {
+		name      string
+		user      *domain.User
+		changed   bool
+		outdated  bool
+		saved     bool
+		rewrapped bool
+	}{
+		{
+			name:     "secret key and unkeyed hashes",
+			user:     hashed(newUser("1", nil, withSecretKey(testSecretKey)), util.HashString(phoneNumber), util.HashString(email)),
+			outdated: true,
+			saved:    true,
+		},
+		{
+			name:     "old secret key",
+			user:     hashed(newUser("2", nil, withSecretKey(testOldSecretKey)), blindIndex(conf, phoneNumber), blindIndex(conf, email)),
+			outdated: true,
+			saved:    true,
+		},
+		{
+			name:      "data key wrapped by old master key",
+			user:      hashed(newUser("3", oldKeys, withDataKey(oldKeys)), blindIndex(conf, phoneNumber), blindIndex(conf, email)),
+			outdated:  true,
+			saved:     true,
+			rewrapped: true,
+		},
+		{
+			name:     "current keys",
+			user:     hashed(newUser("4", keys, withDataKey(keys)), blindIndex(conf, phoneNumber), blindIndex(conf, email)),
+			outdated: false,
+		},
+		{
+			name:     "changed in the meantime",
+			user:     hashed(newUser("5", nil, withSecretKey(testSecretKey)), util.HashString(phoneNumber), util.HashString(email)),
+			changed:  true,
+			outdated: true,
+			saved:    false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeUserRepository{stored: map[string]domain.UserCiphertexts{tt.user.ID: ciphertextsOf(tt.user)}}
+			if tt.changed {
+				c := repo.stored[tt.user.ID]
+				c.PhoneNumberEncrypted = mustEncrypt("9999999999", testSecretKey)
+				repo.stored[tt.user.ID] = c
+			}
+			before := repo.stored[tt.user.ID]
+			rs := &ReencryptionService{repo: repo, keys: keys, config: conf}
+
+			outdated, saved, err := rs.reencryptUser(ctx, tt.user)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if outdated != tt.outdated || saved != tt.saved {
+				t.Fatalf("reencryptUser() = (%v, %v), want (%v, %v)", outdated, saved, tt.outdated, tt.saved)
+			}
+			stored := repo.stored[tt.user.ID]
+			if !tt.saved {
+				if tt.outdated && stored.PhoneNumberEncrypted != before.PhoneNumberEncrypted {
+					t.Errorf("reencryptUser() overwrote the changed phone number")
+				}
+				return
+			}
+
+			if !strings.HasPrefix(*stored.DataKey, keys.CurrentKeyPrefix()) {
+				t.Errorf("data key %q is not wrapped by the current master key", *stored.DataKey)
+			}
+			if tt.rewrapped && *stored.DataKey == *before.DataKey {
+				t.Errorf("data key was not wrapped again")
+			}
+			if stored.PhoneNumberHash != blindIndex(conf, phoneNumber) {
+				t.Errorf("phone number hash = %q, want %q", stored.PhoneNumberHash, blindIndex(conf, phoneNumber))
+			}
+			if *stored.EmailHash != blindIndex(conf, email) {
+				t.Errorf("email hash = %q, want %q", *stored.EmailHash, blindIndex(conf, email))
+			}
+
+			// The saved ciphertexts decrypt to the original data with the stored data key only
+			reread := &domain.User{DataKey: stored.DataKey}
+			for _, field := range []struct {
+				encStr string
+				want   string
+			}{
+				{stored.PhoneNumberEncrypted, phoneNumber},
+				{*stored.EmailEncrypted, email},
+				{*stored.TOTPSecretEncrypted, secret},
+			} {
+				if !util.IsDataKeyEncrypted(field.encStr) {
+					t.Errorf("ciphertext %q is not encrypted with the data key", field.encStr)
+				}
+				got, err := decryptUserData(ctx, keys, reread, field.encStr, nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got != field.want {
+					t.Errorf("decrypted %q, want %q", got, field.want)
+				}
+			}
+
+			// Running again finds nothing left to re-encrypt
+			tt.user.DataKey = stored.DataKey
+			tt.user.PhoneNumberEncrypted = stored.PhoneNumberEncrypted
+			tt.user.PhoneNumberHash = stored.PhoneNumberHash
+			tt.user.EmailEncrypted = stored.EmailEncrypted
+			tt.user.EmailHash = stored.EmailHash
+			tt.user.TOTPSecretEncrypted = stored.TOTPSecretEncrypted
+			outdated, _, err = rs.reencryptUser(ctx, tt.user)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if outdated {
+				t.Errorf("reencryptUser() reports the re-encrypted user outdated")
+			}
+		})
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"encoding/base64"
 	"fmt"
 	"strings"
+	"time"
 
 	"golang.org/x/crypto/argon2"
 
@@ -19,18 +20,33 @@ import (
 
 // UserService struct represents the user service with its dependencies
 type UserService struct {
-	repo port.IUserRepository // user repository interface
-	log  *logger.Logger       // logger instance
+	repo     port.IUserRepository      // user repository interface
+	authSvc  port.IAuthService         // auth service revoking the tokens of changed accounts
+	keys     port.IKeyManager          // key manager wrapping the data keys of users
+	cache    port.ICache               // cache holding the failed login counters and pending email changes
+	notifier port.INotificationService // notification service delivering email verification codes
+	log      *logger.Logger            // logger instance
+	config   *config.App               // app configuration
 }
 
 // NewUserService constructor function
-func NewUserService(repo port.IUserRepository, log *logger.Logger) port.IUserService {
+func NewUserService(repo port.IUserRepository, authSvc port.IAuthService, keys port.IKeyManager, cache port.ICache, notifier port.INotificationService, log *logger.Logger, config *config.App) port.IUserService {
 	return &UserService{
-		repo: repo,
-		log:  log,
+		repo:     repo,
+		authSvc:  authSvc,
+		keys:     keys,
+		cache:    cache,
+		notifier: notifier,
+		log:      log,
+		config:   config,
 	}
 }
 
+const (
+	defaultUserPageSize = 20
+	maxUserPageSize     = 100
+)
+
 type params struct {
 	memory      uint32
 	iterations  uint32
@@ -39,22 +55,41 @@ type params struct {
 	keyLength   uint32
 }
 
-// Register function: encrypt phone number, generate ID if needed, upsert user
+// Register function: encrypt phone number with the user's data key, generate ID if needed, upsert user
 func (us *UserService) Register(ctx context.Context, user *domain.User, config *config.App) (*domain.User, error) {
-	phoneNumberEnc, err := util.EncryptString(user.PhoneNumber, config.SecretKey)
+	phoneNumberEnc, err := encryptUserData(ctx, us.repo, us.keys, user, user.PhoneNumber)
 	if err != nil {
 		return nil, err
 	}
+	var outbox []*domain.OutboxMessage
 	if user.ID == "" {
 		user.ID = util.GenerateULID()
+
+		// Welcome new users once the registration is committed
+		welcome, err := newOutboxMessage(domain.OutboxTopicNotification, domain.NotificationEvent{
+			UserID:   user.ID,
+			Channel:  domain.ChannelSMS,
+			Template: domain.TemplateWelcome,
+			Data: map[string]any{
+				"app_name":   config.Name,
+				"first_name": user.FirstName,
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+		outbox = append(outbox, welcome)
 	}
 
 	if user.Email != nil {
-		emailEnc, err := util.EncryptString(*user.Email, config.SecretKey)
+		if err := us.checkEmailAvailable(ctx, user.ID, *user.Email); err != nil {
+			return nil, err
+		}
+		emailEnc, err := encryptUserData(ctx, us.repo, us.keys, user, *user.Email)
 		if err != nil {
 			return nil, err
 		}
-		emailHash := util.HashString(*user.Email)
+		emailHash := blindIndex(us.config, *user.Email)
 		user.EmailEncrypted = &emailEnc
 		user.EmailHash = &emailHash
 	}
@@ -66,10 +101,10 @@ func (us *UserService) Register(ctx context.Context, user *domain.User, config *
 		}
 		user.Password = &passwordHash
 	}
-	user.PhoneNumberHash = util.HashString(user.PhoneNumber)
+	user.PhoneNumberHash = blindIndex(us.config, user.PhoneNumber)
 	user.PhoneNumberEncrypted = phoneNumberEnc
 
-	usr, err := us.repo.UpsertUser(ctx, user)
+	usr, err := us.repo.UpsertUser(ctx, user, outbox...)
 	if err != nil {
 		return nil, err
 	}
@@ -87,17 +122,17 @@ func (us *UserService) GetUser(ctx context.Context, id string, config *config.Ap
 
 // GetUserByPhoneNumberOrEmail function: retrieve user by phone number hash or email hash
 func (us *UserService) GetUserByPhoneNumberOrEmail(ctx context.Context, str string) (*domain.User, error) {
-	hash := util.HashString(str)
-	usr, err := us.repo.GetUserByPhoneNumberOrEmail(ctx, hash)
+	usr, err := us.repo.GetUserByPhoneNumberOrEmail(ctx, blindIndexes(us.config, str)...)
 	if err != nil {
 		return nil, err
 	}
 	return usr, nil
 }
 
-// GetUserAndComparePassword function: retrieve user by phone number hash or email hash and compare the password
+// GetUserAndComparePassword function: retrieve user by phone number hash or email hash and compare the password.
+// Attempts are counted per user before comparing and lock the account after too many failures.
 func (us *UserService) GetUserAndComparePassword(ctx context.Context, email, password string) (*domain.User, bool, error) {
-	user, err := us.GetUserByPhoneNumberOrEmail(ctx, email)
+	user, err := us.repo.GetUserByPhoneNumberOrEmail(ctx, blindIndexes(us.config, email)...)
 	if err != nil {
 		return nil, false, err
 	}
@@ -105,12 +140,184 @@ func (us *UserService) GetUserAndComparePassword(ctx context.Context, email, pas
 		return nil, false, domain.ErrPasswordNotSet
 	}
 
+	attempts, err := us.reserveLoginAttempt(ctx, user.ID)
+	if err != nil {
+		return nil, false, err
+	}
 	match, err := comparePasswordAndHash(password, *user.Password)
 	if err != nil {
 		return nil, false, err
 	}
 
-	return user, match, nil
+	if !match {
+		if err := us.recordLoginFailure(ctx, user.ID, attempts); err != nil {
+			return nil, false, err
+		}
+		return user, false, nil
+	}
+	if err := us.resetLoginFailures(ctx, user.ID); err != nil {
+		return nil, false, err
+	}
+	// An email only identifies the user once its owner verified it
+	if user.PhoneNumber != email && !user.IsEmailVerified {
+		return nil, false, domain.ErrEmailNotVerified
+	}
+	return user, true, nil
+}
+
+// UpdateProfile updates the allowed profile fields. A changed email or phone number is not written to the user,
+// a verification code is sent to it instead and the change takes effect once the code is verified.
+func (us *UserService) UpdateProfile(ctx context.Context, userID string, patch *domain.UserPatch) (*domain.User, error) {
+	if patch.IsEmpty() {
+		return nil, domain.ErrNoUpdatedData
+	}
+	user, err := us.repo.GetUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if patch.Email != nil && (user.Email == nil || *patch.Email != *user.Email) {
+		if err := us.ChangeEmail(ctx, userID, *patch.Email, user.Locale); err != nil {
+			return nil, err
+		}
+	}
+	if patch.PhoneNumber != nil && *patch.PhoneNumber != user.PhoneNumber {
+		if err := us.ChangePhoneNumber(ctx, userID, *patch.PhoneNumber, user.Locale); err != nil {
+			return nil, err
+		}
+	}
+
+	var columns []string
+	if patch.FirstName != nil && *patch.FirstName != user.FirstName {
+		user.FirstName = *patch.FirstName
+		columns = append(columns, "first_name")
+	}
+	if patch.LastName != nil && *patch.LastName != user.LastName {
+		user.LastName = *patch.LastName
+		columns = append(columns, "last_name")
+	}
+	if len(columns) == 0 {
+		return user, nil
+	}
+
+	return us.repo.UpdateUser(ctx, user, columns...)
+}
+
+// ConfirmPhoneNumber marks the phone number verified after a successful OTP verification, the first
+// verification activates the account unless an admin has ever deactivated it
+func (us *UserService) ConfirmPhoneNumber(ctx context.Context, user *domain.User) (*domain.User, error) {
+	if !user.IsPhoneNumberVerified {
+		user.IsPhoneNumberVerified = true
+		columns := []string{"is_phone_number_verified"}
+		if !user.IsActive && user.DeactivatedAt == nil {
+			user.IsActive = true
+			columns = append(columns, "is_active")
+		}
+		var err error
+		user, err = us.repo.UpdateUser(ctx, user, columns...)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !user.IsActive {
+		return nil, domain.ErrAccountInactive
+	}
+	return user, nil
+}
+
+// ListUsers lists a page of users, one more user than the page size is fetched to know whether another page follows
+func (us *UserService) ListUsers(ctx context.Context, filter *domain.UserFilter, search string) (*domain.UserPage, error) {
+	switch {
+	case filter.Limit <= 0:
+		filter.Limit = defaultUserPageSize
+	case filter.Limit > maxUserPageSize:
+		filter.Limit = maxUserPageSize
+	}
+	if search != "" {
+		filter.IdentifierHashes = blindIndexes(us.config, search)
+	}
+
+	pageSize := filter.Limit
+	filter.Limit++
+	users, err := us.repo.ListUsers(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &domain.UserPage{Users: users}
+	if len(users) > pageSize {
+		page.Users = users[:pageSize]
+		page.NextCursor = page.Users[pageSize-1].ID
+	}
+	return page, nil
+}
+
+// SetRole changes the role of the user and revokes the user's tokens, which carry the old role
+func (us *UserService) SetRole(ctx context.Context, id string, role domain.UserRole) (*domain.User, error) {
+	if !role.IsValid() {
+		return nil, domain.ErrValidation
+	}
+	user, err := us.repo.GetUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if user.Role == role {
+		return user, nil
+	}
+
+	user.Role = role
+	if _, err := us.repo.UpdateUser(ctx, user, "role"); err != nil {
+		return nil, err
+	}
+	us.log.Info().Str("user_id", id).Str("role", string(role)).Msg("User role changed")
+	return user, us.authSvc.RevokeAllJWT(ctx, id)
+}
+
+// SetActive activates or deactivates the account, deactivation revokes every token of the user
+// so the account is logged out immediately. Access tokens are rejected for inactive users even when
+// the revocation fails.
+func (us *UserService) SetActive(ctx context.Context, id string, active bool) (*domain.User, error) {
+	user, err := us.repo.GetUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if user.IsActive == active {
+		return user, nil
+	}
+
+	user.IsActive = active
+	columns := []string{"is_active"}
+	if !active {
+		now := time.Now()
+		user.DeactivatedAt = &now
+		columns = append(columns, "deactivated_at")
+	}
+	if _, err := us.repo.UpdateUser(ctx, user, columns...); err != nil {
+		return nil, err
+	}
+	us.log.Info().Str("user_id", id).Bool("is_active", active).Msg("User status changed")
+	if !active {
+		return user, us.authSvc.RevokeAllJWT(ctx, id)
+	}
+	return user, nil
+}
+
+// DeleteUser soft deletes the account and revokes every token of the user, the erasure job erases
+// the personal data once the deletion grace period has passed
+func (us *UserService) DeleteUser(ctx context.Context, id, deletedBy string) error {
+	if err := us.repo.SoftDeleteUser(ctx, id, deletedBy, time.Now()); err != nil {
+		return err
+	}
+	us.log.Info().Str("user_id", id).Str("deleted_by", deletedBy).Msg("User deleted")
+	return us.authSvc.RevokeAllJWT(ctx, id)
+}
+
+// UnlockUser clears the failed logins and lockouts of the user
+func (us *UserService) UnlockUser(ctx context.Context, id string) error {
+	if _, err := us.repo.GetUser(ctx, id); err != nil {
+		return err
+	}
+	return us.resetLoginFailures(ctx, id)
 }
 
 func generatePasswordHash(password string) (string, error) {
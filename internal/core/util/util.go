@@ -3,9 +3,15 @@ package util
 import (
 	"crypto/aes"
 	"crypto/cipher"
+	"crypto/hmac"
 	"crypto/rand"
 	"crypto/sha256"
+	"encoding/base64"
 	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
 	"time"
 
 	"github.com/oklog/ulid/v2"
@@ -18,6 +24,28 @@ func GenerateULID() string {
 	return id.String()
 }
 
+// GenerateToken: create a URL safe random token from n random bytes
+func GenerateToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// GenerateNumericCode: create a random code of n digits
+func GenerateNumericCode(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := io.ReadFull(rand.Reader, b); err != nil {
+		return "", err
+	}
+	const charset = "1234567890"
+	for i := range b {
+		b[i] = charset[int(b[i])%len(charset)]
+	}
+	return string(b), nil
+}
+
 // HashString: generate SHA-256 hash of input string
 func HashString(str string) string {
 	hash := sha256.New()
@@ -26,6 +54,18 @@ func HashString(str string) string {
 	return hex.EncodeToString(hb)
 }
 
+// BlindIndex: generate keyed HMAC-SHA256 hash of input string, prefixed with the version tag of the key
+func BlindIndex(str string, key string, version uint) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(str))
+	return BlindIndexPrefix(version) + hex.EncodeToString(mac.Sum(nil))
+}
+
+// BlindIndexPrefix: version tag of the blind indexes computed with the given key version
+func BlindIndexPrefix(version uint) string {
+	return fmt.Sprintf("bi%d:", version)
+}
+
 // EncryptString: encrypt input string using AES-GCM encryption
 func EncryptString(str string, secret string) (string, error) {
 	key := []byte(secret)
@@ -75,3 +115,40 @@ func DecryptString(encStr string, secret string) (string, error) {
 	}
 	return string(pt[:]), nil
 }
+
+// DataKeyPrefix marks ciphertexts encrypted with a user's data key, ciphertexts without it are encrypted with the secret key
+const DataKeyPrefix = "dk1:"
+
+// EncryptWithDataKey: encrypt input string with a data key using AES-GCM encryption, the ciphertext is prefixed with DataKeyPrefix
+func EncryptWithDataKey(str string, dataKey []byte) (string, error) {
+	encStr, err := EncryptString(str, string(dataKey))
+	if err != nil {
+		return "", err
+	}
+	return DataKeyPrefix + encStr, nil
+}
+
+// DecryptField: decrypt a ciphertext of EncryptWithDataKey with the data key, ciphertexts without the prefix are
+// decrypted with the first of the secret keys that opens them
+func DecryptField(encStr string, dataKey []byte, secrets ...string) (string, error) {
+	encStr, ok := strings.CutPrefix(encStr, DataKeyPrefix)
+	if !ok {
+		err := errors.New("ciphertext requires a secret key")
+		for _, secret := range secrets {
+			var str string
+			if str, err = DecryptString(encStr, secret); err == nil {
+				return str, nil
+			}
+		}
+		return "", err
+	}
+	if dataKey == nil {
+		return "", errors.New("ciphertext requires a data key")
+	}
+	return DecryptString(encStr, string(dataKey))
+}
+
+// IsDataKeyEncrypted reports whether the ciphertext is encrypted with a data key
+func IsDataKeyEncrypted(encStr string) bool {
+	return strings.HasPrefix(encStr, DataKeyPrefix)
+}
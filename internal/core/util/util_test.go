@@ -0,0 +1,125 @@
+package util
+
+import (
+	"strings"
+	"testing"
+)
+
+const (
+	testSecret    = "0123456789abcdef0123456789abcdef"
+	testOldSecret = "fedcba9876543210fedcba9876543210"
+	testDataKey   = "abcdefghijklmnopqrstuvwxyz012345"
+)
+
+func TestBlindIndex(t *testing.T) {
+	tests := []struct {
+		name    string
+		key     string
+		version uint
+		prefix  string
+	}{
+		{"version 1", "key-1", 1, "bi1:"},
+		{"version 2", "key-2", 2, "bi2:"},
+		{"version 10", "key-10", 10, "bi10:"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hash := BlindIndex("9876543210", tt.key, tt.version)
+			if !strings.HasPrefix(hash, tt.prefix) {
+				t.Errorf("BlindIndex() = %q, want prefix %q", hash, tt.prefix)
+			}
+			if prefix := BlindIndexPrefix(tt.version); prefix != tt.prefix {
+				t.Errorf("BlindIndexPrefix() = %q, want %q", prefix, tt.prefix)
+			}
+			if again := BlindIndex("9876543210", tt.key, tt.version); again != hash {
+				t.Errorf("BlindIndex() is not deterministic, got %q and %q", hash, again)
+			}
+		})
+	}
+
+	t.Run("key separation", func(t *testing.T) {
+		a := BlindIndex("9876543210", "key-a", 1)
+		b := BlindIndex("9876543210", "key-b", 1)
+		if a == b {
+			t.Errorf("BlindIndex() is the same for different keys: %q", a)
+		}
+		if unkeyed := HashString("9876543210"); strings.HasSuffix(a, unkeyed) {
+			t.Errorf("BlindIndex() matches the unkeyed hash %q", unkeyed)
+		}
+	})
+}
+
+func TestDecryptField(t *testing.T) {
+	current, err := EncryptString("current", testSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	old, err := EncryptString("old", testOldSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dataKeyEnc, err := EncryptWithDataKey("data key", []byte(testDataKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		encStr  string
+		dataKey []byte
+		secrets []string
+		want    string
+		wantErr bool
+	}{
+		{"current secret key", current, nil, []string{testSecret, testOldSecret}, "current", false},
+		{"falls back to old secret key", old, nil, []string{testSecret, testOldSecret}, "old", false},
+		{"old secret key listed first", current, nil, []string{testOldSecret, testSecret}, "current", false},
+		{"no matching secret key", old, nil, []string{testSecret}, "", true},
+		{"no secret keys", current, nil, nil, "", true},
+		{"data key", dataKeyEnc, []byte(testDataKey), []string{testSecret}, "data key", false},
+		{"data key ignores secret keys", dataKeyEnc, nil, []string{testDataKey}, "", true},
+		{"missing data key", dataKeyEnc, nil, []string{testSecret, testOldSecret}, "", true},
+		{"wrong data key", dataKeyEnc, []byte(testSecret), nil, "", true},
+		{"secret key ciphertext with data key", current, []byte(testDataKey), []string{testSecret}, "current", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecryptField(tt.encStr, tt.dataKey, tt.secrets...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DecryptField() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("DecryptField() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncryptWithDataKey(t *testing.T) {
+	tests := []struct {
+		name string
+		str  string
+	}{
+		{"phone number", "9876543210"},
+		{"email", "example@example.com"},
+		{"empty", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encStr, err := EncryptWithDataKey(tt.str, []byte(testDataKey))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !IsDataKeyEncrypted(encStr) {
+				t.Errorf("EncryptWithDataKey() = %q, want prefix %q", encStr, DataKeyPrefix)
+			}
+			got, err := DecryptField(encStr, []byte(testDataKey))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.str {
+				t.Errorf("DecryptField() = %q, want %q", got, tt.str)
+			}
+		})
+	}
+}